@@ -0,0 +1,107 @@
+package log
+
+import "reflect"
+import "runtime"
+import "strconv"
+import "strings"
+
+
+// ------------------------------------------------
+// Caller
+
+
+// The location in source code where a log message is made.
+type Caller struct {
+    File string     // Full path of the source file.
+    Line int
+    Func string     // Full name of the function, e.g. "main.(*Server).Serve".
+}
+
+
+// Format the caller as "dir/file.go:line", only the last directory of the file is kept. An empty string is returned if the caller is unknown.
+func (this Caller) String() string {
+    if this.File == "" {
+        return ""
+    }
+
+    file := this.File
+    if idx := strings.LastIndex(file, "/"); idx >= 0 {
+        if idx2 := strings.LastIndex(file[:idx], "/"); idx2 >= 0 {
+            file = file[idx2+1:]
+        }
+    }
+
+    return file + ":" + strconv.Itoa(this.Line)
+}
+
+
+// Prefix of the full names of functions in this package, e.g. "github.com/m3ng9i/go-utils/log.".
+var pkgPrefix = func() string {
+    name := runtime.FuncForPC(reflect.ValueOf(New).Pointer()).Name()
+    return strings.TrimSuffix(name, "New")
+}()
+
+
+// Check if a function is a method of Logger or a wrapper of it, whose frame should be skipped to find the caller.
+func isLoggerFrame(function string) bool {
+    // The standard library's log and log/slog packages.
+    if strings.HasPrefix(function, "log.") || strings.HasPrefix(function, "log/slog.") {
+        return true
+    }
+    if !strings.HasPrefix(function, pkgPrefix) {
+        return false
+    }
+    name := function[len(pkgPrefix):]
+    return strings.HasPrefix(name, "(*Logger).") || strings.HasPrefix(name, "(*SlogHandler).") || strings.HasPrefix(name, "(*levelWriter).") || name == "Output" || name == "Outputf"
+}
+
+
+// Get the caller of Logger's methods, i.e. the first frame out of Logger's methods in the current goroutine's stack. If withStack is true, the stack trace from the caller is returned too, in the same format as runtime/debug.Stack() but without the goroutine header and logger's frames.
+func getCaller(withStack bool) (caller Caller, stack string) {
+
+    pcs := make([]uintptr, 64)
+    for {
+        // Skip runtime.Callers and getCaller.
+        n := runtime.Callers(2, pcs)
+        if n < len(pcs) || !withStack {
+            pcs = pcs[:n]
+            break
+        }
+        pcs = make([]uintptr, len(pcs) * 2)
+    }
+
+    // A pc may have several frames because of inlining, so frames are checked one by one rather than pcs.
+    frames := runtime.CallersFrames(pcs)
+    found := false
+    var b strings.Builder
+
+    for {
+        frame, more := frames.Next()
+
+        if !found {
+            if isLoggerFrame(frame.Function) && more {
+                continue
+            }
+            found = true
+            caller = Caller{File: frame.File, Line: frame.Line, Func: frame.Function}
+            if !withStack {
+                return
+            }
+        } else {
+            b.WriteByte('\n')
+        }
+
+        b.WriteString(frame.Function)
+        b.WriteString("\n\t")
+        b.WriteString(frame.File)
+        b.WriteByte(':')
+        b.WriteString(strconv.Itoa(frame.Line))
+
+        if !more {
+            break
+        }
+    }
+
+    stack = b.String()
+    return
+}
@@ -0,0 +1,74 @@
+package log
+
+import "testing"
+import "bytes"
+import "fmt"
+import "runtime"
+import "strings"
+
+
+func TestCaller(t *testing.T) {
+    var buf bytes.Buffer
+
+    var config Config
+    config.Layout      = LY_MSGONLY
+    config.LayoutStyle = "{caller}: {msg}"
+    config.Caller      = true
+
+    logger, err := New(&buf, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    _, _, line, _ := runtime.Caller(0)
+    logger.Info("info")
+    logger.With("k", "v").Warnw("warn")
+    logger.Printf(ERROR, "printf")
+    logger.Write([]byte("write"))
+    logger.Wait()
+
+    var expected string
+    for i, msg := range []string{"info", "warn", "printf", "write"} {
+        expected += fmt.Sprintf("log/caller_test.go:%d: %s\n", line + i + 1, msg)
+    }
+    if buf.String() != expected {
+        t.Errorf("Unexpected output: %q", buf.String())
+    }
+}
+
+
+func TestStack(t *testing.T) {
+    var sink messageSink
+
+    var config Config
+    config.Level      = INFO
+    config.Stack      = true
+    config.StackLevel = ERROR
+
+    logger, err := New(&bytes.Buffer{}, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+    err = logger.AddSink(&sink, INFO)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    logger.Warn("no stack")
+    logger.Error("stack")
+    logger.Wait()
+
+    if len(sink) != 2 {
+        t.Fatalf("Unexpected messages: %v", sink)
+    }
+    if sink[0].Stack != "" || sink[0].Caller.File != "" {
+        t.Errorf("Stack should not be recorded: %q", sink[0].Stack)
+    }
+    if !strings.HasPrefix(sink[1].Stack, pkgPrefix + "TestStack\n\t") {
+        t.Errorf("Unexpected stack: %q", sink[1].Stack)
+    }
+    // Config.Caller is false, so caller is not recorded with stack.
+    if sink[1].Caller.File != "" {
+        t.Errorf("Caller should not be recorded: %v", sink[1].Caller)
+    }
+}
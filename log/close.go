@@ -0,0 +1,130 @@
+package log
+
+import "context"
+import "errors"
+import "io"
+import "os"
+import "sync/atomic"
+
+
+// Returned by methods of a closed Logger.
+var ErrClosed = errors.New("log: logger is closed")
+
+
+/* Close the logger. It stops accepting new messages, writes messages left in the queue, waits for user defined functions and background jobs like compressing rotated files, then closes all sinks. Writers of sinks are closed if they implement io.Closer, except stdout and stderr.
+
+If ctx is done before all these finish, messages left in the queue are dropped, sinks are closed in background, and ctx.Err() is returned.
+
+After Close, Print, Printf, Printw and Write return ErrClosed.
+
+Example:
+    ctx, cancel := context.WithTimeout(context.Background(), 5 * time.Second)
+    defer cancel()
+    logger.Close(ctx)
+*/
+func (this *Logger) Close(ctx context.Context) error {
+
+    this.state.closeMu.Lock()
+    if atomic.LoadInt32(&this.state.closed) != 0 {
+        this.state.closeMu.Unlock()
+        return ErrClosed
+    }
+    atomic.StoreInt32(&this.state.closed, 1)
+    this.state.closeMu.Unlock()
+
+    // The log writing goroutine keeps running, so errors reported by background jobs could be written.
+    waited := make(chan struct{})
+    go func() {
+        this.wg.Wait()
+        close(waited)
+    }()
+
+    select {
+        case <-waited:
+        case <-ctx.Done():
+            close(this.state.abort)
+            close(this.state.quit)
+            return ctx.Err()
+    }
+
+    close(this.state.quit)
+
+    select {
+        case <-this.state.done:
+            return this.state.closeErr
+        case <-ctx.Done():
+            return ctx.Err()
+    }
+}
+
+
+// Write or drop messages left in the queue, close all sinks, must be called in the log writing goroutine before it exits.
+func (this *Logger) shutdown() {
+
+    for {
+        select {
+            case msg := <-this.jobs:
+                this.process(msg)
+                continue
+            default:
+        }
+        break
+    }
+
+    // Sinks may be blocked if Close() has timed out, so nothing more is written.
+    select {
+        case <-this.state.abort:
+        default:
+            this.reportRepeated()
+            this.reportDropped()
+            this.reportWriteErrors()
+    }
+
+    sinks := []Sink{this.primary}
+    for _, entry := range this.state.sinks {
+        sinks = append(sinks, entry.sink)
+    }
+
+    for _, sink := range sinks {
+        if closer, ok := sink.(io.Closer); ok {
+            if err := closer.Close(); err != nil && this.state.closeErr == nil {
+                this.state.closeErr = err
+            }
+        }
+    }
+
+    close(this.state.done)
+}
+
+
+// Run f in the log writing goroutine. If the logger is closed, f is not run and ErrClosed is returned.
+func (this *Logger) run(f func()) error {
+    select {
+        case this.ctrl <- f:
+            return nil
+        case <-this.state.done:
+            return ErrClosed
+    }
+}
+
+
+// Stop the time-based rotation, and close the writer if it implements io.Closer and is not stdout or stderr.
+func (this *WriterSink) Close() error {
+
+    if this.timer != nil {
+        this.timer.Stop()
+    }
+
+    if this.lock != nil {
+        this.lock.close()
+    }
+
+    if file, ok := this.w.(*os.File); ok && (file == os.Stdout || file == os.Stderr || this.openFailed) {
+        return nil
+    }
+
+    if closer, ok := this.w.(io.Closer); ok {
+        return closer.Close()
+    }
+    return nil
+}
@@ -0,0 +1,134 @@
+package log
+
+import "testing"
+import "context"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "time"
+
+
+func TestClose(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+
+    var config Config
+    config.Layout      = LY_MSGONLY
+    config.LayoutStyle = "{msg}"
+
+    logger, err := New(file, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for i := 0; i < 100; i++ {
+        logger.Info("message")
+    }
+
+    err = logger.Close(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    b, err := ioutil.ReadFile(filepath.Join(dir, "test.log"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(b) != 100 * len("message\n") {
+        t.Errorf("Unexpected size of log file: %d", len(b))
+    }
+
+    // The file should be closed.
+    if _, err = file.Write([]byte("x")); err == nil {
+        t.Error("Log file is not closed.")
+    }
+
+    if _, err = logger.Print(ERROR, "after close"); err != ErrClosed {
+        t.Errorf("Unexpected error: %v", err)
+    }
+    if _, err = logger.With("k", "v").Write([]byte("after close")); err != ErrClosed {
+        t.Errorf("Unexpected error: %v", err)
+    }
+    if err = logger.Reopen(); err != ErrClosed {
+        t.Errorf("Unexpected error: %v", err)
+    }
+    if err = logger.Close(context.Background()); err != ErrClosed {
+        t.Errorf("Unexpected error: %v", err)
+    }
+}
+
+
+func TestCloseTimeout(t *testing.T) {
+    w := newBlockWriter()
+
+    logger, err := New(w, Config{})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    logger.Info("blocked")
+    <-w.entered
+    logger.Info("dropped")
+
+    ctx, cancel := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+    defer cancel()
+
+    err = logger.Close(ctx)
+    if err != context.DeadlineExceeded {
+        t.Errorf("Unexpected error: %v", err)
+    }
+
+    close(w.release)
+    logger.Wait()
+}
+
+
+// Close() should return at the deadline even if senders are blocked by a full queue and a stalled sink.
+func TestCloseBlockedSender(t *testing.T) {
+    w := newBlockWriter()
+    defer close(w.release)
+
+    logger, err := New(w, Config{QueueSize: 1})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    logger.Info("stalled")
+    <-w.entered
+    logger.Info("queued")
+
+    sent := make(chan error, 1)
+    go func() {
+        _, err := logger.Print(INFO, "blocked")
+        sent <- err
+    }()
+
+    // Wait for the sender to block on the full queue.
+    time.Sleep(50 * time.Millisecond)
+
+    ctx, cancel := context.WithTimeout(context.Background(), 50 * time.Millisecond)
+    defer cancel()
+
+    closed := make(chan error, 1)
+    go func() {
+        closed <- logger.Close(ctx)
+    }()
+
+    select {
+        case err = <-closed:
+            if err != context.DeadlineExceeded {
+                t.Errorf("Unexpected error: %v", err)
+            }
+        case <-time.After(time.Second):
+            t.Fatal("Close() does not return at the deadline.")
+    }
+
+    select {
+        case err = <-sent:
+            if err != ErrClosed {
+                t.Errorf("Unexpected error of the blocked sender: %v", err)
+            }
+        case <-time.After(time.Second):
+            t.Fatal("The blocked sender does not return after Close() times out.")
+    }
+}
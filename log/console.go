@@ -0,0 +1,149 @@
+package log
+
+import "fmt"
+import "io"
+import "os"
+import "strings"
+import "unicode/utf8"
+
+
+// ------------------------------------------------
+// ConsoleFormatter
+
+
+// ANSI color codes of levels.
+var levelColors = map[LevelType]string{
+    DEBUG:  "90",       // gray
+    NOTICE: "36",       // cyan
+    INFO:   "32",       // green
+    WARN:   "33",       // yellow
+    ERROR:  "31",       // red
+    FATAL:  "1;31",     // bold red
+}
+
+
+// ANSI color code of less important parts, e.g. caller and stack trace.
+const dimColor = "90"
+
+
+// Width of the longest level name, i.e. "NOTICE".
+const levelWidth = 6
+
+
+/* Format a message for reading in terminal: the level is colored and padded to the same width, messages are padded so fields are aligned, and keys of fields are colored as the level.
+
+Marks in Style are the same as TemplateFormatter. Newlines in message are kept as is, and the stack trace is written in the lines after the message.
+
+Use NewConsoleFormatter() to turn color on only when it's supported.
+
+Example:
+    15:04:05.000 INFO   main.go:12 user login                               user=1001 ip=10.0.0.1
+    15:04:05.001 WARN   main.go:20 slow request                             path=/api latency=1.2s
+*/
+type ConsoleFormatter struct {
+    Style       string  // Layout style. If empty, LS_CONSOLE is used.
+    TimeFormat  string  // Time format. If empty, "15:04:05.000" is used.
+    MsgWidth    int     // Messages shorter than MsgWidth are padded with spaces before fields. If zero, 40 is used. If negative, messages are not padded.
+    Color       bool    // If color the output by ANSI codes.
+}
+
+
+// Create a ConsoleFormatter for writing to w. Color is on if w is a terminal and the NO_COLOR environment variable is not set.
+func NewConsoleFormatter(w io.Writer) *ConsoleFormatter {
+    return &ConsoleFormatter{Color: isColorSupported(w)}
+}
+
+
+// Check if colored output could be written to w, i.e. w is a terminal and NO_COLOR is not set. See https://no-color.org.
+func isColorSupported(w io.Writer) bool {
+
+    if os.Getenv("NO_COLOR") != "" {
+        return false
+    }
+
+    file, ok := w.(*os.File)
+    return ok && isTerminal(file)
+}
+
+
+// Wrap s with an ANSI color code if Color is true.
+func (this *ConsoleFormatter) paint(s, color string) string {
+    if !this.Color || color == "" || s == "" {
+        return s
+    }
+    return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
+
+
+// Format fields like Fields.String(), with colored keys.
+func (this *ConsoleFormatter) fields(fields Fields, color string) string {
+    var b strings.Builder
+    for i, f := range fields {
+        if i > 0 {
+            b.WriteByte(' ')
+        }
+        b.WriteString(this.paint(quoteIfNeeded(f.Key), color))
+        b.WriteByte('=')
+        b.WriteString(quoteIfNeeded(fmt.Sprint(f.Value)))
+    }
+    return b.String()
+}
+
+
+func (this *ConsoleFormatter) Format(m Message) []byte {
+
+    style := this.Style
+    if style == "" {
+        style = LS_CONSOLE
+    }
+
+    timeFormat := this.TimeFormat
+    if timeFormat == "" {
+        timeFormat = "15:04:05.000"
+    }
+
+    msgWidth := this.MsgWidth
+    if msgWidth == 0 {
+        msgWidth = 40
+    }
+
+    color := levelColors[m.Level]
+
+    level := m.Level.String()
+    if n := levelWidth - len(level); n > 0 {
+        level = this.paint(level, color) + strings.Repeat(" ", n)
+    } else {
+        level = this.paint(level, color)
+    }
+
+    msg := m.Msg
+    if len(m.Fields) > 0 && !strings.Contains(msg, "\n") {
+        if n := msgWidth - utf8.RuneCountInString(msg); n > 0 {
+            msg += strings.Repeat(" ", n)
+        }
+    }
+
+    marks := []string{"{time}", m.Time.Format(timeFormat),
+                "{level}", level,
+                "{msg}", msg}
+
+    // If a mark is empty, remove the space before it too, as TemplateFormatter does.
+    for _, mark := range []struct{ name, value string }{
+        {"{fields}", this.fields(m.Fields, color)},
+        {"{caller}", this.paint(m.Caller.String(), dimColor)},
+        {"{name}", m.Name},
+    } {
+        if mark.value == "" {
+            marks = append(marks, " " + mark.name, "")
+        }
+        marks = append(marks, mark.name, mark.value)
+    }
+
+    s := strings.NewReplacer(marks...).Replace(style)
+
+    if m.Stack != "" {
+        s = strings.TrimRight(s, "\n") + "\n" + this.paint(m.Stack, dimColor)
+    }
+
+    return appendNewline([]byte(s))
+}
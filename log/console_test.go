@@ -0,0 +1,71 @@
+package log
+
+import "testing"
+import "bytes"
+import "os"
+import "time"
+
+
+func TestConsoleFormatter(t *testing.T) {
+
+    m := Message{
+        Msg:    "user login",
+        Time:   time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC),
+        Level:  INFO,
+        Fields: Fields{F("user", 1001), F("name", "a b")},
+    }
+
+    tests := []struct {
+        formatter   ConsoleFormatter
+        m           Message
+        expected    string
+    }{
+        {ConsoleFormatter{MsgWidth: 12}, m, "15:04:05.000 INFO   user login   user=1001 name=\"a b\"\n"},
+        {ConsoleFormatter{MsgWidth: -1, Color: true}, m, "15:04:05.000 \x1b[32mINFO\x1b[0m   user login \x1b[32muser\x1b[0m=1001 \x1b[32mname\x1b[0m=\"a b\"\n"},
+        {ConsoleFormatter{Style: "{level} {msg} {fields}"}, Message{Msg: "multiple\nlines", Level: NOTICE}, "NOTICE multiple\nlines\n"},
+        {ConsoleFormatter{Color: true}, Message{Msg: "failed", Level: ERROR, Caller: Caller{File: "/src/app/main.go", Line: 12}, Stack: "main.main"},
+            "00:00:00.000 \x1b[31mERROR\x1b[0m  \x1b[90mapp/main.go:12\x1b[0m failed\n\x1b[90mmain.main\x1b[0m\n"},
+    }
+
+    for i, test := range tests {
+        if result := string(test.formatter.Format(test.m)); result != test.expected {
+            t.Errorf("%d: expect %q, got %q", i, test.expected, result)
+        }
+    }
+}
+
+
+func TestNewConsoleFormatter(t *testing.T) {
+
+    if NewConsoleFormatter(&bytes.Buffer{}).Color {
+        t.Error("Color should be off for a buffer.")
+    }
+
+    // /dev/null is a character device, but not a terminal.
+    null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer null.Close()
+
+    t.Setenv("NO_COLOR", "")
+    if NewConsoleFormatter(null).Color {
+        t.Error("Color should be off for " + os.DevNull)
+    }
+
+    // The master side of a pseudo terminal.
+    pty, err := os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+    if err != nil {
+        t.Skip("Pseudo terminal is not available: ", err)
+    }
+    defer pty.Close()
+
+    if !NewConsoleFormatter(pty).Color {
+        t.Error("Color should be on for a terminal.")
+    }
+
+    t.Setenv("NO_COLOR", "1")
+    if NewConsoleFormatter(pty).Color {
+        t.Error("Color should be off when NO_COLOR is set.")
+    }
+}
@@ -0,0 +1,102 @@
+package log
+
+import "fmt"
+import "time"
+
+
+// ------------------------------------------------
+// Duplicate message suppression
+
+
+// Check if two messages are identical, regardless of their time.
+func sameMessage(a, b *Message) bool {
+    return a.Level == b.Level && a.Name == b.Name && a.Msg == b.Msg && a.Caller == b.Caller && a.Fields.String() == b.Fields.String()
+}
+
+
+// Check if a message is identical to the last written one and should be collapsed, as Config.Dedup. A different message makes the collapsed ones reported first. Must be called in the log writing goroutine.
+func (this *Logger) dedup(msg Message) bool {
+    if !this.Dedup {
+        return false
+    }
+
+    if this.state.last != nil && sameMessage(this.state.last, &msg) {
+        this.state.repeated++
+        return true
+    }
+
+    this.reportRepeated()
+    return false
+}
+
+
+// Write a message of how many times the last message is repeated since it's written, at the same level as the last message. Must be called in the log writing goroutine.
+func (this *Logger) reportRepeated() {
+    if this.state.repeated == 0 {
+        return
+    }
+
+    m := newMsg(fmt.Sprintf("last message repeated %d times", this.state.repeated), this.state.last.Level)
+    this.state.repeated = 0
+
+    this.wg.Add(1)
+    this.write(m)
+}
+
+
+// ------------------------------------------------
+// Sampling
+
+
+/* Sampling limits the rate of messages of a level: in every tick, the first "First" messages are written, then every "Thereafter"-th message. Dropped messages are counted and reported as the overflow policy does.
+
+Example:
+    // Write the first 100 DEBUG messages per second, then every 100th.
+    config.Sampling = map[log.LevelType]log.Sampling{
+        log.DEBUG: {First: 100, Thereafter: 100},
+    }
+*/
+type Sampling struct {
+    First       int             // Number of messages written in each tick.
+    Thereafter  int             // After First messages, every Thereafter-th message is written. If zero, the rest are dropped.
+    Tick        time.Duration   // Length of a period. If zero, one second is used.
+}
+
+
+// Counter of messages of a level in the current tick.
+type sampleCounter struct {
+    start time.Time
+    n int
+}
+
+
+// Check if a message should be written as Config.Sampling. Must be called in the log writing goroutine.
+func (this *Logger) sample(msg Message) bool {
+
+    sampling, ok := this.Sampling[msg.Level]
+    if !ok {
+        return true
+    }
+
+    tick := sampling.Tick
+    if tick == 0 {
+        tick = time.Second
+    }
+
+    counter := this.state.samples[msg.Level]
+    if counter == nil {
+        counter = new(sampleCounter)
+        this.state.samples[msg.Level] = counter
+    }
+
+    if msg.Time.Sub(counter.start) >= tick {
+        counter.start = msg.Time
+        counter.n = 0
+    }
+    counter.n++
+
+    if counter.n <= sampling.First {
+        return true
+    }
+    return sampling.Thereafter > 0 && (counter.n - sampling.First) % sampling.Thereafter == 0
+}
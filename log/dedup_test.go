@@ -0,0 +1,86 @@
+package log
+
+import "testing"
+import "bytes"
+import "context"
+import "time"
+
+
+func TestDedup(t *testing.T) {
+    var buf bytes.Buffer
+
+    logger, err := New(&buf, Config{Layout: LY_LEVEL, LayoutStyle: "{level} {msg} {fields}", Level: DEBUG, Dedup: true})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for i := 0; i < 3; i++ {
+        logger.Error("connection refused")
+    }
+    logger.Errorw("connection refused", "host", "db1")
+    logger.Errorw("connection refused", "host", "db1")
+    logger.Info("retry")
+    logger.Info("retry")
+
+    err = logger.Close(context.Background())
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    expected := "ERROR connection refused\n" +
+        "ERROR last message repeated 2 times\n" +
+        "ERROR connection refused host=db1\n" +
+        "ERROR last message repeated 1 times\n" +
+        "INFO retry\n" +
+        "INFO last message repeated 1 times\n"
+    if buf.String() != expected {
+        t.Errorf("Unexpected output: %q", buf.String())
+    }
+}
+
+
+func TestSampling(t *testing.T) {
+
+    if _, err := New(&bytes.Buffer{}, Config{Sampling: map[LevelType]Sampling{INFO: {First: -1}}}); err == nil {
+        t.Error("Negative sampling should not be accepted.")
+    }
+
+    var sink messageSink
+    logger, err := New(&bytes.Buffer{}, Config{Level: DEBUG, Sampling: map[LevelType]Sampling{
+        DEBUG:  {First: 2, Thereafter: 3, Tick: time.Hour},
+        NOTICE: {First: 1},
+    }})
+    if err != nil {
+        t.Fatal(err)
+    }
+    logger.AddSink(&sink, DEBUG)
+
+    for i := 0; i < 10; i++ {
+        logger.Debugf("debug %d", i)
+        logger.Noticef("notice %d", i)
+        logger.Infof("info %d", i)
+    }
+    logger.Wait()
+
+    counts := make(map[LevelType]int)
+    var debugs []string
+    for _, m := range sink {
+        counts[m.Level]++
+        if m.Level == DEBUG {
+            debugs = append(debugs, m.Msg)
+        }
+    }
+
+    // The 1st, 2nd, 5th and 8th DEBUG messages are written.
+    if len(debugs) != 4 || debugs[2] != "debug 4" || debugs[3] != "debug 7" {
+        t.Errorf("Unexpected DEBUG messages: %v", debugs)
+    }
+    if counts[NOTICE] != 1 || counts[INFO] != 10 {
+        t.Errorf("Unexpected counts: %v", counts)
+    }
+
+    logger.Close(context.Background())
+    if last := sink[len(sink) - 1]; last.Level != WARN || last.Msg != "log: dropped 15 messages" {
+        t.Errorf("Unexpected report of dropped messages: %v", last)
+    }
+}
@@ -2,6 +2,32 @@
 
 With this package, you can create a logger with custom time format and message layout. You can output the log messages to an io.Writer object. If the io.Writer object is a file, you can choose to generate a new log file hourly, daily or monthly, and a datetime will be added to the old log file's filename.
 
+Several processes could write to the same log file if Config.Shared is true. They coordinate by an advisory lock, so the file is rotated once per period, and other processes reopen it after it's rotated.
+
+A logger can write messages to several sinks, e.g. a file and stdout, each with its own level, formatter and rotation settings. See Logger.AddWriter() and Logger.AddSink().
+
+Key/value pairs can be attached to log messages by Logger.With() and the methods like Infow(), and shown by the {fields} mark in the layout style.
+
+Named loggers like "http.auth" are created by Logger.Get(), they share sinks with the logger created by New(). Levels are inherited along dotted names, and could be set by a string like "http.auth=debug,db=warn,*=info" by Logger.SetLevels(). The name is shown by the {name} mark.
+
+For reading in terminal, set Config.Formatter to NewConsoleFormatter(os.Stdout), which colors levels and aligns columns. Color is turned off when the output is not a terminal or NO_COLOR is set.
+
+A logger can be used as the backend of log/slog by NewSlogHandler(), e.g. slog.New(log.NewSlogHandler(logger)).
+
+Output of the standard library's log package can be redirected to a logger by Logger.RedirectStdLog(), and other loggers can write to Logger.Writer().
+
+To avoid flooding the log, identical consecutive messages could be collapsed by Config.Dedup, and the rate of messages of a level could be limited by Config.Sampling.
+
+Secrets like bearer tokens and passwords could be masked by Config.Redact before messages are written.
+
+Log files could be parsed back to messages by Reader, which reads rotated files too, or follows a file like "tail -f".
+
+Messages could be sent to remote ends by SyslogSink (RFC 5424 or RFC 3164 over UDP, TCP or unix socket), JSONSink (newline-delimited JSON over TCP) and HTTPSink (batched HTTP POST). They reconnect with backoff, and keep messages in a bounded spool while the remote end is down.
+
+A logger could be created from a JSON config file by LoadConfigFile() and NewFromConfig(), which use names like "info" and "daily" rather than constants. Environment variables like LOG_LEVEL and LOG_OUTPUT override the config, unless FileConfig.NoEnv is set.
+
+Counters of accepted messages per level, dropped messages, and bytes written, rotations and write errors of each sink are returned by Logger.Stats(), and served in Prometheus text format by Logger.MetricsHandler(). The first write error of a sink is reported as an ERROR message at once, the following ones are reported periodically and when the sink recovers.
+
 This package do not support mail log, but you can define a function by yourself and use the function to do some extra log processing work, including mail log.
 */
 package log
@@ -0,0 +1,108 @@
+package log
+
+import "fmt"
+import "strconv"
+import "strings"
+import "unicode"
+
+
+// ------------------------------------------------
+// Field
+
+
+// A key/value pair attached to a log message.
+type Field struct {
+    Key     string
+    Value   interface{}
+}
+
+
+// Fields of a log message, in the order they were added.
+type Fields []Field
+
+
+// Make a Field.
+func F(key string, value interface{}) Field {
+    return Field{Key: key, Value: value}
+}
+
+
+/* Convert alternating keys and values to Fields.
+
+Each element of kv can be a Field, or a key followed by its value. A key that is not a string is converted by fmt.Sprint. A value without a key is stored under the key "!BADKEY".
+
+Example:
+    toFields("user", 1001, F("latency", time.Second))
+    // Output: user=1001 latency=1s
+*/
+func toFields(kv ...interface{}) Fields {
+    if len(kv) == 0 {
+        return nil
+    }
+
+    fields := make(Fields, 0, len(kv)/2+1)
+
+    for i := 0; i < len(kv); i++ {
+        if f, ok := kv[i].(Field); ok {
+            fields = append(fields, f)
+            continue
+        }
+
+        if i == len(kv)-1 {
+            fields = append(fields, Field{Key: "!BADKEY", Value: kv[i]})
+            break
+        }
+
+        key, ok := kv[i].(string)
+        if !ok {
+            key = fmt.Sprint(kv[i])
+        }
+        fields = append(fields, Field{Key: key, Value: kv[i+1]})
+        i++
+    }
+
+    return fields
+}
+
+
+// Join two Fields into a new one, elements of a are placed before elements of b.
+func joinFields(a, b Fields) Fields {
+    if len(a) == 0 {
+        return b
+    }
+    if len(b) == 0 {
+        return a
+    }
+    fields := make(Fields, 0, len(a)+len(b))
+    fields = append(fields, a...)
+    return append(fields, b...)
+}
+
+
+// Format fields like "key1=value1 key2=value2". A value containing spaces, quotes, "=" or control characters will be quoted.
+func (this Fields) String() string {
+    var b strings.Builder
+    for i, f := range this {
+        if i > 0 {
+            b.WriteByte(' ')
+        }
+        b.WriteString(quoteIfNeeded(f.Key))
+        b.WriteByte('=')
+        b.WriteString(quoteIfNeeded(fmt.Sprint(f.Value)))
+    }
+    return b.String()
+}
+
+
+// Quote a string by strconv.Quote if it is empty or contains spaces, quotes, "=" or non-printable characters.
+func quoteIfNeeded(s string) string {
+    if s == "" {
+        return `""`
+    }
+    for _, r := range s {
+        if r == '"' || r == '=' || r == '\\' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+            return strconv.Quote(s)
+        }
+    }
+    return s
+}
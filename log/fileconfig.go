@@ -0,0 +1,496 @@
+package log
+
+import "bytes"
+import "context"
+import "encoding/json"
+import "fmt"
+import "io"
+import "io/ioutil"
+import "math"
+import "os"
+import "strconv"
+import "strings"
+import "time"
+
+
+// ------------------------------------------------
+// ConfigLevel, ByteSize and Duration
+
+
+// A log level unmarshaled from a name like "info", case-insensitive, or a number like LevelType's.
+type ConfigLevel LevelType
+
+
+// Marshal the level as its name.
+func (this ConfigLevel) MarshalText() ([]byte, error) {
+    if !LevelType(this).Legal() {
+        return nil, fmt.Errorf("Log level is not legal: %d", this)
+    }
+    return []byte(LevelType(this).String()), nil
+}
+
+
+// Unmarshal a level name or number.
+func (this *ConfigLevel) UnmarshalText(b []byte) error {
+    s := strings.TrimSpace(string(b))
+    level, ok := String2Level(s)
+    if !ok {
+        n, err := strconv.Atoi(s)
+        if err != nil || !LevelType(n).Legal() {
+            return fmt.Errorf("Log level is not legal: %s", s)
+        }
+        level = LevelType(n)
+    }
+    *this = ConfigLevel(level)
+    return nil
+}
+
+
+// Unmarshal a JSON string or number.
+func (this *ConfigLevel) UnmarshalJSON(b []byte) error {
+    var s string
+    if json.Unmarshal(b, &s) == nil {
+        return this.UnmarshalText([]byte(s))
+    }
+    return this.UnmarshalText(b)
+}
+
+
+// A size in bytes, unmarshaled from a number, or a string like "100MB". Units are B, KB, MB and GB, in multiples of 1024.
+type ByteSize int64
+
+
+func (this *ByteSize) UnmarshalText(b []byte) error {
+
+    s := strings.ToUpper(strings.TrimSpace(string(b)))
+
+    unit := int64(1)
+    for _, u := range []struct{ suffix string; size int64 }{
+        {"GB", 1 << 30}, {"MB", 1 << 20}, {"KB", 1 << 10}, {"G", 1 << 30}, {"M", 1 << 20}, {"K", 1 << 10}, {"B", 1},
+    } {
+        if strings.HasSuffix(s, u.suffix) {
+            s, unit = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.size
+            break
+        }
+    }
+
+    n, err := strconv.ParseInt(s, 10, 64)
+    if err != nil || n < 0 || n > math.MaxInt64 / unit {
+        return fmt.Errorf("Size is not legal: %s", b)
+    }
+    *this = ByteSize(n * unit)
+    return nil
+}
+
+
+func (this *ByteSize) UnmarshalJSON(b []byte) error {
+    var s string
+    if json.Unmarshal(b, &s) == nil {
+        return this.UnmarshalText([]byte(s))
+    }
+    return this.UnmarshalText(b)
+}
+
+
+// A duration unmarshaled from a string like "720h" by time.ParseDuration, or "30d" for days.
+type Duration time.Duration
+
+
+func (this *Duration) UnmarshalText(b []byte) error {
+    s := strings.TrimSpace(string(b))
+
+    if strings.HasSuffix(s, "d") {
+        days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+        if err != nil || days < 0 {
+            return fmt.Errorf("Duration is not legal: %s", s)
+        }
+        *this = Duration(time.Duration(days) * 24 * time.Hour)
+        return nil
+    }
+
+    d, err := time.ParseDuration(s)
+    if err != nil {
+        return fmt.Errorf("Duration is not legal: %s", s)
+    }
+    *this = Duration(d)
+    return nil
+}
+
+
+// ------------------------------------------------
+// FileConfig
+
+
+// Settings of an output, i.e. the primary output of a logger, or a sink writing to a file, stdout or stderr.
+type OutputConfig struct {
+    Level           ConfigLevel `json:"level"`              // Level name like "info".
+    Output          string      `json:"output"`             // "stdout" (default), "stderr", or a file path.
+    Format          string      `json:"format"`             // "text" (default), "json", "logfmt" or "console".
+    Layout          string      `json:"layout"`             // Layout style of "text" format, a name like "fields" for LS_FIELDS, or a style like "{time} {msg}".
+    TimeFormat      string      `json:"time_format"`        // A name like "normal" for TF_NORMAL, "rfc3339", or a time format like "2006-01-02 15:04:05".
+    Utc             bool        `json:"utc"`
+    Rotate          string      `json:"rotate"`             // "none" (default), "hourly", "daily" or "monthly".
+    RotatePattern   string      `json:"rotate_pattern"`
+    MaxSize         ByteSize    `json:"max_size"`           // e.g. "100MB".
+    MaxFiles        int         `json:"max_files"`
+    MaxAge          Duration    `json:"max_age"`            // e.g. "30d".
+    Compress        bool        `json:"compress"`
+    Shared          bool        `json:"shared"`
+}
+
+
+// Settings of a sink added to a logger.
+type SinkConfig struct {
+    Type            string      `json:"type"`               // "output" (default), "syslog", "json" or "http".
+    OutputConfig                                            // Settings of "output" type. Level is used by all types.
+    Network         string      `json:"network"`            // Network of "syslog" and "json" types, e.g. "udp" or "tcp".
+    Addr            string      `json:"addr"`               // Address of "syslog" and "json" types.
+    URL             string      `json:"url"`                // URL of "http" type.
+    SyslogFormat    string      `json:"syslog_format"`      // "rfc5424" (default) or "rfc3164".
+    Tag             string      `json:"tag"`                // Application name of "syslog" type.
+    Facility        int         `json:"facility"`           // Facility code of "syslog" type.
+}
+
+
+/* FileConfig is a declarative logger configuration, which could be unmarshaled from JSON. Names are used instead of constants, e.g. "daily" for R_DAILY. See LoadConfigFile() and NewFromConfig().
+
+Example:
+    {
+        "level": "info",
+        "output": "/var/log/app.log",
+        "layout": "fields",
+        "rotate": "daily",
+        "max_files": 30,
+        "compress": true,
+        "levels": "db=warn,http.auth=debug",
+        "sinks": [
+            {"type": "output", "output": "stderr", "level": "error", "format": "console"},
+            {"type": "syslog", "network": "udp", "addr": "localhost:514", "level": "warn"}
+        ]
+    }
+*/
+type FileConfig struct {
+    OutputConfig                                            // The primary output.
+    Levels          string      `json:"levels"`             // Levels of named loggers, see Logger.SetLevels().
+    Caller          bool        `json:"caller"`
+    Stack           bool        `json:"stack"`
+    StackLevel      ConfigLevel `json:"stack_level"`
+    QueueSize       int         `json:"queue_size"`
+    Overflow        string      `json:"overflow"`           // "block" (default), "drop_newest", "drop_oldest" or "drop_below".
+    OverflowLevel   ConfigLevel `json:"overflow_level"`
+    Dedup           bool        `json:"dedup"`
+    NoEnv           bool        `json:"no_env"`             // Do not override the config by environment variables in NewFromConfig().
+    Sinks           []SinkConfig `json:"sinks"`
+}
+
+
+// Read a FileConfig from a JSON file. Unknown keys are taken as errors.
+func LoadConfigFile(filename string) (config FileConfig, err error) {
+
+    b, err := ioutil.ReadFile(filename)
+    if err != nil {
+        return
+    }
+
+    decoder := json.NewDecoder(bytes.NewReader(b))
+    decoder.DisallowUnknownFields()
+    err = decoder.Decode(&config)
+    if err != nil {
+        err = fmt.Errorf("%s: %v", filename, err)
+    }
+    return
+}
+
+
+/* Override config by environment variables with the prefix, e.g. "LOG_LEVEL=debug" when prefix is "LOG_". Supported names are:
+    LEVEL, LEVELS, OUTPUT, FORMAT, LAYOUT, TIME_FORMAT, UTC, ROTATE, MAX_SIZE, MAX_FILES, MAX_AGE, COMPRESS, CALLER
+*/
+func (this *FileConfig) LoadEnv(prefix string) error {
+
+    parseBool := func(p *bool) func(string) error {
+        return func(s string) (err error) {
+            *p, err = strconv.ParseBool(s)
+            return
+        }
+    }
+    parseString := func(p *string) func(string) error {
+        return func(s string) error {
+            *p = s
+            return nil
+        }
+    }
+
+    vars := []struct {
+        name string
+        parse func(string) error
+    }{
+        {"LEVEL",       func(s string) error { return this.Level.UnmarshalText([]byte(s)) }},
+        {"LEVELS",      parseString(&this.Levels)},
+        {"OUTPUT",      parseString(&this.Output)},
+        {"FORMAT",      parseString(&this.Format)},
+        {"LAYOUT",      parseString(&this.Layout)},
+        {"TIME_FORMAT", parseString(&this.TimeFormat)},
+        {"UTC",         parseBool(&this.Utc)},
+        {"ROTATE",      parseString(&this.Rotate)},
+        {"MAX_SIZE",    func(s string) error { return this.MaxSize.UnmarshalText([]byte(s)) }},
+        {"MAX_FILES",   func(s string) (err error) { this.MaxFiles, err = strconv.Atoi(s); return }},
+        {"MAX_AGE",     func(s string) error { return this.MaxAge.UnmarshalText([]byte(s)) }},
+        {"COMPRESS",    parseBool(&this.Compress)},
+        {"CALLER",      parseBool(&this.Caller)},
+    }
+
+    for _, v := range vars {
+        if s, ok := os.LookupEnv(prefix + v.name); ok {
+            if err := v.parse(s); err != nil {
+                return fmt.Errorf("%s%s: %v", prefix, v.name, err)
+            }
+        }
+    }
+    return nil
+}
+
+
+// Get a value by name from a table, an empty name gets the default value, i.e. the first one.
+func lookupName(kind, name string, names []string, values []int) (int, error) {
+    if name == "" {
+        return values[0], nil
+    }
+    for i, n := range names {
+        if strings.EqualFold(n, name) {
+            return values[i], nil
+        }
+    }
+    return 0, fmt.Errorf("%s is not legal: %s", kind, name)
+}
+
+
+// Convert names of time formats to TF_* constants, other strings are used as is.
+func timeFormatByName(name string) string {
+    switch strings.ToLower(name) {
+        case "default":     return TF_DEFAULT
+        case "normal":      return TF_NORMAL
+        case "long":        return TF_LONG
+        case "time":        return TF_TIME
+        case "timelong":    return TF_TIMELONG
+        case "rfc3339":     return time.RFC3339
+        case "rfc3339nano": return time.RFC3339Nano
+    }
+    return name
+}
+
+
+// Convert names of layout styles to LS_* constants, other strings are used as is.
+func layoutStyleByName(name string) string {
+    switch strings.ToLower(name) {
+        case "default":     return LS_DEFAULT
+        case "simple":      return LS_SIMPLE
+        case "fields":      return LS_FIELDS
+        case "caller":      return LS_CALLER
+        case "console":     return LS_CONSOLE
+    }
+    return name
+}
+
+
+// Open the output. Files opened are appended to files, so they could be closed on errors.
+func (this *OutputConfig) open(files *[]*os.File) (io.Writer, error) {
+    switch strings.ToLower(this.Output) {
+        case "", "stdout":
+            return os.Stdout, nil
+        case "stderr":
+            return os.Stderr, nil
+    }
+    file, err := OpenFile(this.Output)
+    if err != nil {
+        return nil, err
+    }
+    *files = append(*files, file)
+    return file, nil
+}
+
+
+// Convert the output settings to Config, for writing to w.
+func (this *OutputConfig) config(w io.Writer) (config Config, err error) {
+
+    config.Level         = LevelType(this.Level)
+    config.Utc           = this.Utc
+    config.RotatePattern = this.RotatePattern
+    config.MaxSize       = int64(this.MaxSize)
+    config.MaxFiles      = this.MaxFiles
+    config.MaxAge        = time.Duration(this.MaxAge)
+    config.Compress      = this.Compress
+    config.Shared        = this.Shared
+    config.TimeFormat    = timeFormatByName(this.TimeFormat)
+    config.LayoutStyle   = layoutStyleByName(this.Layout)
+
+    // The layout elements are decided by the style.
+    if config.LayoutStyle != "" {
+        if strings.Contains(config.LayoutStyle, "{time}") {
+            config.Layout |= LY_TIME
+        }
+        if strings.Contains(config.LayoutStyle, "{level}") {
+            config.Layout |= LY_LEVEL
+        }
+        if config.Layout == 0 {
+            config.Layout = LY_MSGONLY
+        }
+    }
+
+    config.Rotate, err = lookupName("Rotate", this.Rotate,
+        []string{"none", "hourly", "daily", "monthly"},
+        []int{R_NONE, R_HOURLY, R_DAILY, R_MONTHLY})
+    if err != nil {
+        return
+    }
+
+    switch strings.ToLower(this.Format) {
+        case "", "text":
+        case "json":
+            config.Formatter = &JSONFormatter{TimeFormat: timeFormatByName(this.TimeFormat)}
+        case "logfmt":
+            config.Formatter = &LogfmtFormatter{TimeFormat: timeFormatByName(this.TimeFormat)}
+        case "console":
+            formatter := NewConsoleFormatter(w)
+            formatter.Style = layoutStyleByName(this.Layout)
+            formatter.TimeFormat = timeFormatByName(this.TimeFormat)
+            config.Formatter = formatter
+        default:
+            err = fmt.Errorf("Format is not legal: %s", this.Format)
+    }
+    return
+}
+
+
+// Create a sink by its settings.
+func (this *SinkConfig) sink(files *[]*os.File) (sink Sink, err error) {
+
+    switch strings.ToLower(this.Type) {
+        case "", "output":
+            var w io.Writer
+            w, err = this.open(files)
+            if err != nil {
+                return
+            }
+            var config Config
+            config, err = this.config(w)
+            if err != nil {
+                return
+            }
+            return NewWriterSink(w, config)
+
+        case "syslog":
+            var config SyslogConfig
+            config.Format, err = lookupName("Syslog format", this.SyslogFormat,
+                []string{"rfc5424", "rfc3164"},
+                []int{SF_RFC5424, SF_RFC3164})
+            if err != nil {
+                return
+            }
+            config.Tag      = this.Tag
+            config.Facility = this.Facility
+            return NewSyslogSink(this.Network, this.Addr, config)
+
+        case "json":
+            return NewJSONSink(this.Network, this.Addr, NetConfig{})
+
+        case "http":
+            return NewHTTPSink(this.URL, HTTPConfig{})
+    }
+
+    err = fmt.Errorf("Sink type is not legal: %s", this.Type)
+    return
+}
+
+
+/* Create a Logger by a FileConfig. Environment variables with the prefix "LOG_" override the config unless FileConfig.NoEnv is true, see FileConfig.LoadEnv().
+
+Example:
+    config, err := log.LoadConfigFile("log.json")
+    if err != nil {
+        return err
+    }
+    logger, err := log.NewFromConfig(config)
+*/
+func NewFromConfig(fc FileConfig) (logger *Logger, err error) {
+
+    if !fc.NoEnv {
+        err = fc.LoadEnv("LOG_")
+        if err != nil {
+            return
+        }
+    }
+
+    // Files opened and sinks created are closed if the logger is not created.
+    var files []*os.File
+    var sinks []Sink
+    defer func() {
+        if err != nil {
+            for _, sink := range sinks {
+                if closer, ok := sink.(io.Closer); ok {
+                    closer.Close()
+                }
+            }
+            for _, file := range files {
+                file.Close()
+            }
+            logger = nil
+        }
+    }()
+
+    w, err := fc.open(&files)
+    if err != nil {
+        return
+    }
+
+    config, err := fc.config(w)
+    if err != nil {
+        return
+    }
+
+    config.Caller        = fc.Caller
+    config.Stack         = fc.Stack
+    config.StackLevel    = LevelType(fc.StackLevel)
+    config.QueueSize     = fc.QueueSize
+    config.OverflowLevel = LevelType(fc.OverflowLevel)
+    config.Dedup         = fc.Dedup
+
+    config.Overflow, err = lookupName("Overflow policy", fc.Overflow,
+        []string{"block", "drop_newest", "drop_oldest", "drop_below"},
+        []int{OF_BLOCK, OF_DROP_NEWEST, OF_DROP_OLDEST, OF_DROP_BELOW})
+    if err != nil {
+        return
+    }
+
+    for i := range fc.Sinks {
+        var sink Sink
+        sink, err = fc.Sinks[i].sink(&files)
+        if err != nil {
+            err = fmt.Errorf("Sink %d: %v", i, err)
+            return
+        }
+        sinks = append(sinks, sink)
+    }
+
+    logger, err = New(w, config)
+    if err != nil {
+        return
+    }
+
+    if fc.Levels != "" {
+        err = logger.SetLevels(fc.Levels)
+    }
+
+    // Sinks added are removed from the slice, they will be closed by the logger, and the others by the deferred function.
+    for i := 0; err == nil && len(sinks) > 0; i++ {
+        err = logger.AddSink(sinks[0], LevelType(fc.Sinks[i].Level))
+        if err == nil {
+            sinks = sinks[1:]
+        }
+    }
+
+    if err != nil {
+        logger.Close(context.Background())
+    }
+    return
+}
+
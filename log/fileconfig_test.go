@@ -0,0 +1,235 @@
+package log
+
+import "testing"
+import "context"
+import "encoding/json"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "strings"
+import "time"
+
+
+func TestByteSizeAndDuration(t *testing.T) {
+
+    sizes := map[string]ByteSize{
+        "100":      100,
+        "10B":      10,
+        "2KB":      2 << 10,
+        "100MB":    100 << 20,
+        "1 gb":     1 << 30,
+        "5m":       5 << 20,
+    }
+    for s, expected := range sizes {
+        var size ByteSize
+        if err := size.UnmarshalText([]byte(s)); err != nil || size != expected {
+            t.Errorf("%q: expected %d, got %d, %v", s, expected, size, err)
+        }
+    }
+    for _, s := range []string{"", "MB", "-1KB", "1TB", "9999999999GB", "9223372036854775808"} {
+        var size ByteSize
+        if size.UnmarshalText([]byte(s)) == nil {
+            t.Errorf("%q should not be accepted.", s)
+        }
+    }
+
+    durations := map[string]time.Duration{
+        "30d":      30 * 24 * time.Hour,
+        "1h30m":    90 * time.Minute,
+    }
+    for s, expected := range durations {
+        var d Duration
+        if err := d.UnmarshalText([]byte(s)); err != nil || time.Duration(d) != expected {
+            t.Errorf("%q: expected %v, got %v, %v", s, expected, time.Duration(d), err)
+        }
+    }
+    for _, s := range []string{"", "xd", "10"} {
+        var d Duration
+        if d.UnmarshalText([]byte(s)) == nil {
+            t.Errorf("%q should not be accepted.", s)
+        }
+    }
+}
+
+
+func TestLoadConfigFile(t *testing.T) {
+
+    dir, err := ioutil.TempDir("", "logconfig")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    filename := filepath.Join(dir, "log.json")
+    err = ioutil.WriteFile(filename, []byte(`{
+        "level": "warn",
+        "output": "app.log",
+        "rotate": "daily",
+        "max_size": "10MB",
+        "max_files": 7,
+        "max_age": "30d",
+        "levels": "db=debug",
+        "sinks": [{"output": "stderr", "level": "error", "format": "json"}]
+    }`), 0644)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    config, err := LoadConfigFile(filename)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if config.Level != ConfigLevel(WARN) || config.Output != "app.log" || config.Rotate != "daily" || config.MaxSize != 10 << 20 ||
+        config.MaxFiles != 7 || time.Duration(config.MaxAge) != 30 * 24 * time.Hour || config.Levels != "db=debug" {
+        t.Errorf("Unexpected config: %+v", config)
+    }
+    if len(config.Sinks) != 1 || config.Sinks[0].Output != "stderr" || config.Sinks[0].Level != ConfigLevel(ERROR) || config.Sinks[0].Format != "json" {
+        t.Errorf("Unexpected sinks: %+v", config.Sinks)
+    }
+
+    for _, content := range []string{`{"level": "verbose"}`, `{"max_size": "big"}`, `{"unknown": 1}`} {
+        ioutil.WriteFile(filename, []byte(content), 0644)
+        if _, err = LoadConfigFile(filename); err == nil {
+            t.Errorf("%s should not be accepted.", content)
+        }
+    }
+}
+
+
+func TestLoadEnv(t *testing.T) {
+
+    os.Setenv("TESTLOG_LEVEL", "debug")
+    os.Setenv("TESTLOG_MAX_FILES", "3")
+    os.Setenv("TESTLOG_COMPRESS", "true")
+    defer os.Unsetenv("TESTLOG_LEVEL")
+    defer os.Unsetenv("TESTLOG_MAX_FILES")
+    defer os.Unsetenv("TESTLOG_COMPRESS")
+
+    config := FileConfig{OutputConfig: OutputConfig{Level: ConfigLevel(ERROR), Output: "stderr"}}
+    if err := config.LoadEnv("TESTLOG_"); err != nil {
+        t.Fatal(err)
+    }
+    if config.Level != ConfigLevel(DEBUG) || config.MaxFiles != 3 || !config.Compress || config.Output != "stderr" {
+        t.Errorf("Unexpected config: %+v", config)
+    }
+
+    os.Setenv("TESTLOG_LEVEL", "verbose")
+    if err := config.LoadEnv("TESTLOG_"); err == nil || !strings.Contains(err.Error(), "TESTLOG_LEVEL") {
+        t.Errorf("Unexpected error: %v", err)
+    }
+}
+
+
+func TestNewFromConfig(t *testing.T) {
+
+    dir, err := ioutil.TempDir("", "logconfig")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer os.RemoveAll(dir)
+
+    filename := filepath.Join(dir, "app.log")
+    errFilename := filepath.Join(dir, "error.log")
+
+    os.Setenv("LOG_LEVEL", "info")
+    defer os.Unsetenv("LOG_LEVEL")
+
+    logger, err := NewFromConfig(FileConfig{
+        OutputConfig: OutputConfig{
+            Level:      ConfigLevel(WARN),
+            Output:     filename,
+            Layout:     "{level} {name}: {msg}",
+            Rotate:     "daily",
+        },
+        Levels: "db=debug",
+        Sinks: []SinkConfig{
+            {OutputConfig: OutputConfig{Level: ConfigLevel(ERROR), Output: errFilename, Format: "logfmt", TimeFormat: "rfc3339"}},
+        },
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    logger.Debug("hidden")
+    logger.Info("info")
+    logger.Get("db").Debug("query")
+    logger.Error("failed")
+    logger.Close(context.Background())
+
+    b, err := ioutil.ReadFile(filename)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if expected := "INFO: info\nDEBUG db: query\nERROR: failed\n"; string(b) != expected {
+        t.Errorf("Expected %q, got %q", expected, b)
+    }
+
+    b, err = ioutil.ReadFile(errFilename)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !strings.Contains(string(b), "level=ERROR msg=failed") || strings.Contains(string(b), "info") {
+        t.Errorf("Unexpected sink output: %q", b)
+    }
+
+    for _, config := range []FileConfig{
+        {OutputConfig: OutputConfig{Format: "xml"}},
+        {OutputConfig: OutputConfig{Rotate: "weekly"}},
+        {OutputConfig: OutputConfig{Rotate: "daily"}},
+        {Overflow: "drop_all"},
+        {Levels: "db"},
+        {Sinks: []SinkConfig{{Type: "mail"}}},
+    } {
+        if _, err = NewFromConfig(config); err == nil {
+            t.Errorf("Config should not be accepted: %+v", config)
+        }
+    }
+}
+
+
+func TestConfigLevel(t *testing.T) {
+
+    var config FileConfig
+    if err := json.Unmarshal([]byte(`{"level": 3, "stack_level": "Error", "sinks": [{"level": "debug"}]}`), &config); err != nil {
+        t.Fatal(err)
+    }
+    if config.Level != ConfigLevel(WARN) || config.StackLevel != ConfigLevel(ERROR) || config.Sinks[0].Level != ConfigLevel(DEBUG) {
+        t.Errorf("Unexpected levels: %+v", config)
+    }
+
+    for _, s := range []string{`{"level": 9}`, `{"level": "verbose"}`, `{"level": true}`} {
+        if json.Unmarshal([]byte(s), &config) == nil {
+            t.Errorf("%s should not be accepted.", s)
+        }
+    }
+
+    if b, err := json.Marshal(ConfigLevel(INFO)); err != nil || string(b) != `"INFO"` {
+        t.Errorf("Unexpected JSON of ConfigLevel: %s, %v", b, err)
+    }
+
+    // LevelType is still encoded as a number.
+    if b, err := json.Marshal(struct{ Level LevelType }{INFO}); err != nil || string(b) != `{"Level":2}` {
+        t.Errorf("Unexpected JSON of LevelType: %s, %v", b, err)
+    }
+}
+
+
+func TestNewFromConfigNoEnv(t *testing.T) {
+
+    os.Setenv("LOG_LEVEL", "verbose")
+    defer os.Unsetenv("LOG_LEVEL")
+
+    if _, err := NewFromConfig(FileConfig{}); err == nil {
+        t.Error("Illegal LOG_LEVEL should not be accepted.")
+    }
+
+    logger, err := NewFromConfig(FileConfig{OutputConfig: OutputConfig{Level: ConfigLevel(ERROR)}, NoEnv: true})
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer logger.Close(context.Background())
+
+    if logger.GetLevel() != ERROR {
+        t.Errorf("Unexpected level: %s", logger.GetLevel())
+    }
+}
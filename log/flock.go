@@ -0,0 +1,50 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package log
+
+import "os"
+import "syscall"
+
+
+// If Config.Shared is supported on this platform.
+const sharedSupported = true
+
+
+// An advisory lock of a file by flock(2), used to coordinate processes sharing a log file.
+type fileLock struct {
+    file *os.File
+}
+
+
+func openFileLock(filename string, mode os.FileMode) (*fileLock, error) {
+    file, err := os.OpenFile(filename, os.O_CREATE|os.O_RDWR, mode)
+    if err != nil {
+        return nil, err
+    }
+    return &fileLock{file: file}, nil
+}
+
+
+// Acquire a shared or exclusive lock. A held lock is converted to the new type, which is not atomic.
+func (this *fileLock) lock(exclusive bool) error {
+    how := syscall.LOCK_SH
+    if exclusive {
+        how = syscall.LOCK_EX
+    }
+    for {
+        err := syscall.Flock(int(this.file.Fd()), how)
+        if err != syscall.EINTR {
+            return err
+        }
+    }
+}
+
+
+func (this *fileLock) unlock() error {
+    return syscall.Flock(int(this.file.Fd()), syscall.LOCK_UN)
+}
+
+
+func (this *fileLock) close() error {
+    return this.file.Close()
+}
@@ -0,0 +1,36 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package log
+
+import "errors"
+import "os"
+
+
+// If Config.Shared is supported on this platform. It needs flock(2), which is not available here.
+const sharedSupported = false
+
+
+var errSharedNotSupported = errors.New("Shared log file is not supported on this platform.")
+
+
+type fileLock struct{}
+
+
+func openFileLock(filename string, mode os.FileMode) (*fileLock, error) {
+    return nil, errSharedNotSupported
+}
+
+
+func (this *fileLock) lock(exclusive bool) error {
+    return errSharedNotSupported
+}
+
+
+func (this *fileLock) unlock() error {
+    return errSharedNotSupported
+}
+
+
+func (this *fileLock) close() error {
+    return nil
+}
@@ -0,0 +1,216 @@
+package log
+
+import "bytes"
+import "encoding/json"
+import "fmt"
+import "strings"
+import "time"
+
+
+// ------------------------------------------------
+// Formatter
+
+
+// Formatter converts a log message to bytes which will be written to io.Writer. The result should end with "\n".
+type Formatter interface {
+    Format(m Message) []byte
+}
+
+
+// Replace newlines in s with "\n" and "\r", to keep a message in one line.
+var newlineEscaper = strings.NewReplacer("\r\n", `\r\n`, "\n", `\n`, "\r", `\r`)
+
+
+// Add a "\n" to the end of b if it does not end with "\n".
+func appendNewline(b []byte) []byte {
+    if len(b) == 0 || b[len(b)-1] != '\n' {
+        b = append(b, '\n')
+    }
+    return b
+}
+
+
+// ------------------------------------------------
+// TemplateFormatter
+
+
+/* Format a message by a layout style like LS_DEFAULT. Marks in Style will be replaced by the message's time, level, msg, fields, caller and name.
+
+Quotes in message are written as is, because the template has no delimiter for message. Newlines in message are escaped unless Multiline is true.
+
+Stack trace of the message is written in the lines after the message if Multiline is true, otherwise it's escaped and written at the end of the line.
+*/
+type TemplateFormatter struct {
+    Style       string  // Layout style, see LS_DEFAULT. If empty, LS_DEFAULT is used.
+    TimeFormat  string  // Time format, see TF_DEFAULT. If empty, TF_DEFAULT is used.
+    Multiline   bool    // Keep newlines in message as is, rather than escape them as "\n".
+}
+
+
+func (this *TemplateFormatter) Format(m Message) []byte {
+
+    style := this.Style
+    if style == "" {
+        style = LS_DEFAULT
+    }
+
+    timeFormat := this.TimeFormat
+    if timeFormat == "" {
+        timeFormat = TF_DEFAULT
+    }
+
+    msg := m.Msg
+    if !this.Multiline {
+        msg = newlineEscaper.Replace(msg)
+    }
+
+    marks := []string{"{time}", m.Time.Format(timeFormat),
+                "{level}", m.Level.String(),
+                "{msg}", msg}
+
+    // If a mark is empty, remove the space before it too, to avoid a trailing space.
+    for _, mark := range []struct{ name, value string }{
+        {"{fields}", m.Fields.String()},
+        {"{caller}", m.Caller.String()},
+        {"{name}", m.Name},
+    } {
+        if mark.value == "" {
+            marks = append(marks, " " + mark.name, "")
+        }
+        marks = append(marks, mark.name, mark.value)
+    }
+
+    s := strings.NewReplacer(marks...).Replace(style)
+
+    if m.Stack != "" {
+        if this.Multiline {
+            s = strings.TrimRight(s, "\n") + "\n" + m.Stack
+        } else {
+            s = strings.TrimRight(s, "\n") + " " + newlineEscaper.Replace(m.Stack)
+        }
+    }
+
+    return appendNewline([]byte(s))
+}
+
+
+// ------------------------------------------------
+// JSONFormatter
+
+
+/* Format a message as one JSON object per line. The object has keys "time", "level", "logger" if the logger is named, "msg", and "caller" and "stack" if they are recorded, followed by the message's fields.
+
+Example:
+    {"time":"2016-01-02T15:04:05.000000+08:00","level":"INFO","msg":"user login","user":1001}
+*/
+type JSONFormatter struct {
+    TimeFormat string   // Time format. If empty, time.RFC3339Nano is used.
+}
+
+
+func (this *JSONFormatter) Format(m Message) []byte {
+
+    timeFormat := this.TimeFormat
+    if timeFormat == "" {
+        timeFormat = time.RFC3339Nano
+    }
+
+    var buf bytes.Buffer
+    buf.WriteString(`{"time":`)
+    writeJSONValue(&buf, m.Time.Format(timeFormat))
+    buf.WriteString(`,"level":`)
+    writeJSONValue(&buf, m.Level.String())
+    if m.Name != "" {
+        buf.WriteString(`,"logger":`)
+        writeJSONValue(&buf, m.Name)
+    }
+    buf.WriteString(`,"msg":`)
+    writeJSONValue(&buf, m.Msg)
+
+    if caller := m.Caller.String(); caller != "" {
+        buf.WriteString(`,"caller":`)
+        writeJSONValue(&buf, caller)
+    }
+
+    if m.Stack != "" {
+        buf.WriteString(`,"stack":`)
+        writeJSONValue(&buf, m.Stack)
+    }
+
+    for _, f := range m.Fields {
+        buf.WriteByte(',')
+        writeJSONValue(&buf, f.Key)
+        buf.WriteByte(':')
+        writeJSONValue(&buf, f.Value)
+    }
+
+    buf.WriteString("}\n")
+    return buf.Bytes()
+}
+
+
+// Write v to buf as JSON. Errors and values that can not be marshaled are written as strings.
+func writeJSONValue(buf *bytes.Buffer, v interface{}) {
+
+    if err, ok := v.(error); ok {
+        v = err.Error()
+    }
+
+    var b bytes.Buffer
+    encoder := json.NewEncoder(&b)
+    encoder.SetEscapeHTML(false)
+
+    if encoder.Encode(v) != nil {
+        b.Reset()
+        encoder.Encode(fmt.Sprint(v))
+    }
+
+    // Encode() adds a "\n" to the end.
+    buf.Write(bytes.TrimRight(b.Bytes(), "\n"))
+}
+
+
+// ------------------------------------------------
+// LogfmtFormatter
+
+
+/* Format a message in logfmt style, with keys "time", "level", "logger" if the logger is named, "msg", and "caller" and "stack" if they are recorded, followed by the message's fields. Values containing spaces, quotes, "=" or newlines are quoted.
+
+Example:
+    time=2016-01-02T15:04:05.000000+08:00 level=INFO msg="user login" user=1001
+*/
+type LogfmtFormatter struct {
+    TimeFormat string   // Time format. If empty, time.RFC3339Nano is used.
+}
+
+
+func (this *LogfmtFormatter) Format(m Message) []byte {
+
+    timeFormat := this.TimeFormat
+    if timeFormat == "" {
+        timeFormat = time.RFC3339Nano
+    }
+
+    fields := make(Fields, 0, len(m.Fields)+6)
+    fields = append(fields,
+        Field{Key: "time", Value: m.Time.Format(timeFormat)},
+        Field{Key: "level", Value: m.Level.String()})
+
+    if m.Name != "" {
+        fields = append(fields, Field{Key: "logger", Value: m.Name})
+    }
+
+    fields = append(fields, Field{Key: "msg", Value: m.Msg})
+
+    if caller := m.Caller.String(); caller != "" {
+        fields = append(fields, Field{Key: "caller", Value: caller})
+    }
+
+    if m.Stack != "" {
+        fields = append(fields, Field{Key: "stack", Value: m.Stack})
+    }
+
+    fields = append(fields, m.Fields...)
+
+    return appendNewline([]byte(fields.String()))
+}
@@ -0,0 +1,62 @@
+package log
+
+import "testing"
+import "fmt"
+import "os"
+import "time"
+
+
+func testMessage() Message {
+    var m Message
+    m.Time = time.Date(2016, 1, 2, 15, 4, 5, 0, time.UTC)
+    m.Level = WARN
+    m.Msg = "line 1\nsay \"hi\""
+    m.Fields = Fields{F("user", 1001), F("path", "/a b")}
+    return m
+}
+
+
+func TestTemplateFormatter(t *testing.T) {
+    f := &TemplateFormatter{Style: LS_FIELDS, TimeFormat: TF_NORMAL}
+    s := string(f.Format(testMessage()))
+    if s != "2016-01-02 15:04:05 WARN: line 1\\nsay \"hi\" user=1001 path=\"/a b\"\n" {
+        t.Errorf("Unexpected output: %q", s)
+    }
+
+    f.Multiline = true
+    s = string(f.Format(testMessage()))
+    if s != "2016-01-02 15:04:05 WARN: line 1\nsay \"hi\" user=1001 path=\"/a b\"\n" {
+        t.Errorf("Unexpected output: %q", s)
+    }
+}
+
+
+func ExampleJSONFormatter() {
+    f := &JSONFormatter{TimeFormat: TF_NORMAL}
+    fmt.Print(string(f.Format(testMessage())))
+    // Output: {"time":"2016-01-02 15:04:05","level":"WARN","msg":"line 1\nsay \"hi\"","user":1001,"path":"/a b"}
+}
+
+
+func ExampleLogfmtFormatter() {
+    f := &LogfmtFormatter{TimeFormat: time.RFC3339}
+    fmt.Print(string(f.Format(testMessage())))
+    // Output: time=2016-01-02T15:04:05Z level=WARN msg="line 1\nsay \"hi\"" user=1001 path="/a b"
+}
+
+
+func ExampleNew_formatter() {
+
+    var config Config
+    config.Level     = INFO
+    config.Formatter = &LogfmtFormatter{TimeFormat: "-"}
+
+    logger, err := New(os.Stdout, config)
+    if err != nil {
+        fmt.Println(err)
+        os.Exit(1)
+    }
+    logger.Infow("user login", "user", 1001)
+    logger.Wait()
+    // Output: time=- level=INFO msg="user login" user=1001
+}
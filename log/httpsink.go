@@ -0,0 +1,114 @@
+package log
+
+import "bytes"
+import "errors"
+import "fmt"
+import "io"
+import "io/ioutil"
+import "net/http"
+import "net/url"
+import "time"
+
+
+// ------------------------------------------------
+// HTTPSink
+
+
+// Settings of HTTPSink.
+type HTTPConfig struct {
+    BatchSize       int             // Max number of messages in a request. If zero, 100 is used.
+    FlushInterval   time.Duration   // How long to wait for a full batch before sending. If zero, 1s is used.
+    Header          http.Header     // Extra headers of requests, e.g. Authorization.
+    Client          *http.Client    // If nil, a client with NetConfig.Timeout is used.
+    NetConfig
+}
+
+
+/* A sink posts messages to a URL in batches. The request body is newline-delimited JSON in the format of JSONFormatter, with Content-Type "application/x-ndjson". A response with status other than 2xx is taken as a failure, and the batch is sent again later, except status 4xx other than 408 and 429, with which the batch is dropped.
+
+Example:
+    sink, err := log.NewHTTPSink("https://logs.example.com/ingest", log.HTTPConfig{
+        Header: http.Header{"Authorization": {"Bearer " + token}},
+    })
+    if err != nil {
+        return err
+    }
+    logger.AddSink(sink, log.INFO)
+*/
+type HTTPSink struct {
+    *shipper
+    url string
+    config HTTPConfig
+    formatter JSONFormatter
+}
+
+
+func NewHTTPSink(rawurl string, config HTTPConfig) (*HTTPSink, error) {
+
+    u, err := url.Parse(rawurl)
+    if err != nil {
+        return nil, err
+    }
+    if u.Scheme != "http" && u.Scheme != "https" {
+        return nil, fmt.Errorf("URL is not legal: %s", rawurl)
+    }
+
+    if config.BatchSize < 0 || config.FlushInterval < 0 {
+        return nil, errors.New("Batch size and flush interval could not be negative.")
+    }
+    if config.BatchSize == 0 {
+        config.BatchSize = 100
+    }
+    if config.FlushInterval == 0 {
+        config.FlushInterval = time.Second
+    }
+
+    setNetConfigDefault(&config.NetConfig)
+    if err := isNetConfigLegal(&config.NetConfig); err != nil {
+        return nil, err
+    }
+
+    if config.Client == nil {
+        config.Client = &http.Client{Timeout: config.Timeout}
+    }
+
+    sink := &HTTPSink{url: rawurl, config: config}
+    sink.shipper = newShipper(config.NetConfig, config.BatchSize, config.FlushInterval, sink.send, nil)
+    return sink, nil
+}
+
+
+// Post a batch of messages, all or none of them are sent.
+func (this *HTTPSink) send(batch [][]byte) (int, error) {
+
+    req, err := http.NewRequest("POST", this.url, bytes.NewReader(bytes.Join(batch, nil)))
+    if err != nil {
+        return 0, err
+    }
+    for key, values := range this.config.Header {
+        req.Header[key] = values
+    }
+    req.Header.Set("Content-Type", "application/x-ndjson")
+
+    resp, err := this.config.Client.Do(req)
+    if err != nil {
+        return 0, err
+    }
+    defer resp.Body.Close()
+    io.Copy(ioutil.Discard, resp.Body)
+
+    if resp.StatusCode / 100 != 2 {
+        err = fmt.Errorf("Unexpected status of %s: %s", this.url, resp.Status)
+        // Client errors except timeout and rate limiting are not fixed by sending again.
+        if resp.StatusCode / 100 == 4 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+            return len(batch), &rejectedError{n: len(batch), err: err}
+        }
+        return 0, err
+    }
+    return len(batch), nil
+}
+
+
+func (this *HTTPSink) WriteMessage(m Message) error {
+    return this.push(this.formatter.Format(m))
+}
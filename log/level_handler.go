@@ -0,0 +1,65 @@
+package log
+
+import "encoding/json"
+import "io/ioutil"
+import "net/http"
+import "strings"
+
+
+// Request and response body of LevelHandler.
+type levelBody struct {
+    Level string `json:"level"`
+}
+
+
+/* Create an http.Handler to get or change the log level at runtime.
+
+GET returns the current level, PUT changes the level. The request body of PUT could be one of the following, level names are case-insensitive:
+    {"level":"debug"}   a JSON object
+    "debug"             a JSON string
+    debug               plain text, surrounding spaces are ignored
+
+Both methods respond with the level in JSON:
+    {"level":"DEBUG"}
+
+Example:
+    http.Handle("/debug/loglevel", logger.LevelHandler())
+
+    // curl -X PUT -d debug http://localhost:8080/debug/loglevel
+*/
+func (this *Logger) LevelHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+        switch r.Method {
+            case "GET", "HEAD":
+
+            case "PUT":
+                b, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, 1024))
+                if err != nil {
+                    http.Error(w, err.Error(), http.StatusBadRequest)
+                    return
+                }
+
+                var body levelBody
+                if json.Unmarshal(b, &body) != nil && json.Unmarshal(b, &body.Level) != nil {
+                    body.Level = strings.TrimSpace(string(b))
+                }
+
+                level, ok := String2Level(body.Level)
+                if !ok {
+                    http.Error(w, "Log level is not legal: " + body.Level, http.StatusBadRequest)
+                    return
+                }
+
+                this.SetLevel(level)
+
+            default:
+                w.Header().Set("Allow", "GET, HEAD, PUT")
+                http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+                return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(levelBody{Level: this.GetLevel().String()})
+    })
+}
@@ -0,0 +1,52 @@
+package log
+
+import "testing"
+import "bytes"
+import "net/http"
+import "net/http/httptest"
+import "strings"
+
+
+func TestLevelHandler(t *testing.T) {
+    var buf bytes.Buffer
+    logger, err := New(&buf, Config{Level: INFO})
+    if err != nil {
+        t.Fatal(err)
+    }
+    child := logger.With("k", "v")
+    handler := logger.LevelHandler()
+
+    tests := []struct {
+        method  string
+        body    string
+        code    int
+        resp    string
+        level   LevelType
+    }{
+        {"GET", "", http.StatusOK, `{"level":"INFO"}`, INFO},
+        {"PUT", `{"level":"debug"}`, http.StatusOK, `{"level":"DEBUG"}`, DEBUG},
+        {"PUT", "warn\n", http.StatusOK, `{"level":"WARN"}`, WARN},
+        {"PUT", `"Notice"`, http.StatusOK, `{"level":"NOTICE"}`, NOTICE},
+        {"PUT", `{"level":"verbose"}`, http.StatusBadRequest, "Log level is not legal: verbose", NOTICE},
+        {"PUT", `"verbose"`, http.StatusBadRequest, "Log level is not legal: verbose", NOTICE},
+        {"PUT", "verbose", http.StatusBadRequest, "Log level is not legal: verbose", NOTICE},
+        {"POST", "", http.StatusMethodNotAllowed, "Method not allowed", NOTICE},
+    }
+
+    for _, test := range tests {
+        r := httptest.NewRequest(test.method, "/level", strings.NewReader(test.body))
+        w := httptest.NewRecorder()
+        handler.ServeHTTP(w, r)
+
+        if w.Code != test.code || strings.TrimSpace(w.Body.String()) != test.resp {
+            t.Errorf("%s %q: unexpected response %d %q", test.method, test.body, w.Code, w.Body.String())
+        }
+        if child.GetLevel() != test.level {
+            t.Errorf("%s %q: unexpected level %s", test.method, test.body, child.GetLevel())
+        }
+    }
+
+    if logger.SetLevel(LevelType(100)) == nil {
+        t.Error("Illegal level should not be set.")
+    }
+}
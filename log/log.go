@@ -6,8 +6,8 @@ import "strings"
 import "time"
 import "fmt"
 import "sync"
+import "sync/atomic"
 import "os"
-import "path/filepath"
 
 
 // ------------------------------------------------
@@ -69,10 +69,13 @@ const (
 )
 
 
-// Log message layout style. Available marks are: {time}, {level} and {msg}.
+// Log message layout style. Available marks are: {time}, {level}, {msg}, {fields}, {caller} and {name}.
 const (
     LS_DEFAULT = "{time} {level}: {msg}"
     LS_SIMPLE = "{time}: {msg}"
+    LS_FIELDS = "{time} {level}: {msg} {fields}"
+    LS_CALLER = "{time} {level} {caller}: {msg}"
+    LS_CONSOLE = "{time} {level} {name} {caller} {msg} {fields}"     // Default style of ConsoleFormatter.
 )
 
 
@@ -94,14 +97,30 @@ const (
 )
 
 
-// Default filename rotate pattern of a file log. Available marks are: {time}, {basename} and {ext}.
-const RP_DEFAULT = "{time}_{basename}{ext}"
+// Default filename rotate pattern of a file log. Available marks are: {time}, {basename}, {ext} and {seq}.
+const (
+    RP_DEFAULT = "{time}_{basename}{ext}"
+    RP_SEQ = "{time}_{seq}_{basename}{ext}"     // Default pattern when Config.MaxSize is set.
+)
 
 
-// Max jobs in Logger.
+// Max jobs in Logger, used when Config.QueueSize is zero.
 const maxJobs = 1024
 
 
+// What to do when the job queue of Logger is full.
+const (
+    OF_BLOCK = iota     // Wait until the queue has room.
+    OF_DROP_NEWEST      // Drop the message being sent.
+    OF_DROP_OLDEST      // Drop the oldest message in the queue to make room.
+    OF_DROP_BELOW       // Drop the message being sent if its level is below Config.OverflowLevel, otherwise wait.
+)
+
+
+// How often the number of dropped messages and repeated messages are reported.
+var dropReportInterval = 10 * time.Second
+
+
 // ------------------------------------------------
 // Utils functions
 
@@ -137,7 +156,8 @@ func isRotateLegal(rotate int) bool {
 }
 
 
-func isRotatePatternLegal(pattern string) error {
+// Check if a rotate pattern is legal. If "seq" is true, the pattern should include {seq}.
+func isRotatePatternLegal(pattern string, seq bool) error {
     if  !strings.Contains(pattern, "{time}") ||
         !strings.Contains(pattern, "{basename}") ||
         !strings.Contains(pattern, "{ext}") {
@@ -145,13 +165,17 @@ func isRotatePatternLegal(pattern string) error {
         return errors.New("Rotate pattern should include {time}, {basename} and {ext}")
     }
 
+    if seq && !strings.Contains(pattern, "{seq}") {
+        return errors.New("Rotate pattern should include {seq} when max size is set.")
+    }
+
     return nil
 }
 
 
-// Check if a writer is legal. Argument "rotate" is a value of Config.Rotate.
-func ifWriterLegal(w io.Writer, rotate int) error {
-    if rotate > R_NONE {
+// Check if a writer is legal. Argument "rotate" means if the writer will be rotated.
+func ifWriterLegal(w io.Writer, rotate bool) error {
+    if rotate {
         file, ok := w.(*os.File)
         if !ok {
             return errors.New("Writer is not file, could not be rotated.")
@@ -179,8 +203,16 @@ func setConfigDefault(config *Config) {
         config.Layout = LY_DEFAULT
     }
 
-    if config.Rotate > R_NONE {
-        config.RotatePattern = RP_DEFAULT
+    if config.QueueSize == 0 {
+        config.QueueSize = maxJobs
+    }
+
+    if config.RotatePattern == "" {
+        if config.MaxSize > 0 {
+            config.RotatePattern = RP_SEQ
+        } else if config.Rotate > R_NONE {
+            config.RotatePattern = RP_DEFAULT
+        }
     }
 }
 
@@ -207,6 +239,27 @@ type Config struct {
     Utc             bool            // If use utc time in output.
     Rotate          int             // How to rotate file log. See R_NONE, R_HOURLY, R_DAILY and R_MONTHLY.
     RotatePattern   string          // Filename rotate pattern of a file log. See RP_DEFAULT for example.
+    MaxSize         int64           // Rotate file log when its size will exceed MaxSize bytes. Zero means no limit. Could be used with Rotate.
+    MaxFiles        int             // Max number of rotated files to keep. Zero means no limit.
+    MaxAge          time.Duration   // Rotated files older than MaxAge will be removed. Zero means no limit.
+    Compress        bool            // If compress rotated files by gzip. The compressed file is named "<rotated-name>.gz".
+    Formatter       Formatter       // Convert log message to bytes. If nil, a TemplateFormatter made by LayoutStyle and TimeFormat is used.
+    QueueSize       int             // Max number of messages waiting to be written. If zero, 1024 is used.
+    Overflow        int             // What to do when the queue is full. See OF_BLOCK, OF_DROP_NEWEST, OF_DROP_OLDEST and OF_DROP_BELOW.
+    OverflowLevel   LevelType       // Messages below this level are dropped when the queue is full, used with OF_DROP_BELOW.
+    Caller          bool            // If record the caller's file, line and function name in messages. See Message.Caller.
+    Stack           bool            // If attach stack traces to messages of StackLevel and above. See Message.Stack.
+    StackLevel      LevelType       // The lowest level of messages with stack traces, used when Stack is true.
+    Dedup           bool            // If collapse identical consecutive messages into "last message repeated N times".
+    Sampling        map[LevelType]Sampling  // Limit the rate of messages of some levels. See Sampling.
+    Redact          *Redactor       // Mask secrets in messages before they are formatted and passed to Handle.Func. See Redactor.
+    Shared          bool            // If the log file is shared by several processes, which coordinate rotation by a lock file "<filename>.lock". Not supported on windows.
+}
+
+
+// If file log will be rotated by time or size.
+func (this *Config) rotatable() bool {
+    return this.Rotate > R_NONE || this.MaxSize > 0
 }
 
 
@@ -218,6 +271,10 @@ type Message struct {
     Msg string
     Time time.Time
     Level LevelType
+    Name string         // Name of the logger making the message, see Logger.Get().
+    Fields Fields       // Key/value pairs attached by Logger.With() and the "w" methods like Infow().
+    Caller Caller       // Where the message is made, only recorded when Config.Caller is true.
+    Stack string        // Stack trace of the goroutine making the message, only recorded when Config.Stack is true.
 }
 
 
@@ -244,15 +301,97 @@ type Handle struct {
 }
 
 
+// ------------------------------------------------
+// waitGroup
+
+
+/* A counter of unfinished jobs, like sync.WaitGroup.
+
+Unlike sync.WaitGroup, Add could be called when the counter is zero and Wait is in progress. It's needed because jobs like time-based rotation are started by timers, not by callers of Wait.
+*/
+type waitGroup struct {
+    mu sync.Mutex
+    cond sync.Cond
+    n int
+}
+
+
+func newWaitGroup() *waitGroup {
+    wg := new(waitGroup)
+    wg.cond.L = &wg.mu
+    return wg
+}
+
+
+func (this *waitGroup) Add(delta int) {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+
+    this.n += delta
+    if this.n < 0 {
+        panic("log: negative wait group counter")
+    }
+    if this.n == 0 {
+        this.cond.Broadcast()
+    }
+}
+
+
+func (this *waitGroup) Done() {
+    this.Add(-1)
+}
+
+
+// Wait until the counter is zero.
+func (this *waitGroup) Wait() {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+
+    for this.n > 0 {
+        this.cond.Wait()
+    }
+}
+
+
 // ------------------------------------------------
 // Logger
 
 type Logger struct {
     Config
-    w io.Writer
     jobs chan Message
-    wg  sync.WaitGroup
+    ctrl chan func()    // Functions run in the log writing goroutine, used to access sinks safely.
+    wg  *waitGroup
     handle *Handle
+    primary *WriterSink // Sink made by the writer and config given to New().
+    state *state        // State shared by the logger and its children.
+    fields Fields       // Fields added to every message of this logger, see With().
+    name string         // Name of the logger, see Get().
+}
+
+
+// State of a Logger, shared with its children created by With().
+type state struct {
+    dropped uint64      // Number of messages dropped since last report, accessed atomically. It's the first field, so it's 64-bit aligned on 32-bit platforms.
+    level int32         // Log level of the primary sink, accessed atomically. See SetLevel().
+    sinks []sinkEntry   // Sinks added by AddSink(), only accessed in the log writing goroutine.
+    sinkLevel int32     // The lowest level of sinks added by AddSink(), accessed atomically.
+    last *Message       // The last written message, used by Config.Dedup. Only accessed in the log writing goroutine, as the fields below.
+    repeated int        // Number of messages identical to last since it's written.
+    samples map[LevelType]*sampleCounter    // Counters of Config.Sampling.
+
+    root *Logger            // The logger created by New().
+    registryMu sync.Mutex   // Protects registry and changes of levels.
+    registry map[string]*Logger     // Named loggers created by Get().
+    levels atomic.Value     // Levels of named loggers set by SetLevels(), a map[string]LevelType which is replaced rather than changed.
+
+    closeMu sync.RWMutex    // Held by senders, so no message is sent after the logger is closed.
+    closed int32            // If the logger is closed, accessed atomically.
+    closeErr error          // Error of closing sinks.
+    quit chan struct{}      // Closed by Close() to stop the log writing goroutine.
+    abort chan struct{}     // Closed when Close() times out, then messages left in queue are dropped.
+    done chan struct{}      // Closed when the log writing goroutine exits.
+
+    counters *loggerCounters    // Counters of Stats().
 }
 
 
@@ -267,29 +406,33 @@ func New(w io.Writer, config Config, handle ...Handle) (logger *Logger, err erro
         return
     }
 
-    err = isLayoutLegal(config.Layout, config.LayoutStyle)
+    primary, err := NewWriterSink(w, config)
     if err != nil {
         return
     }
 
-    if !isRotateLegal(config.Rotate) {
-        err = fmt.Errorf("Rotate is not legal: %d", config.Rotate)
+    if config.QueueSize < 0 {
+        err = fmt.Errorf("Queue size is not legal: %d", config.QueueSize)
         return
     }
 
-    err = ifWriterLegal(w, config.Rotate) 
-    if err != nil {
+    if config.Overflow < OF_BLOCK || config.Overflow > OF_DROP_BELOW {
+        err = fmt.Errorf("Overflow policy is not legal: %d", config.Overflow)
         return
     }
 
-    if config.Rotate > R_NONE {
-        err = isRotatePatternLegal(config.RotatePattern)
-        if err != nil {
+    if config.Stack && !config.StackLevel.Legal() {
+        err = fmt.Errorf("Stack level is not legal: %d", config.StackLevel)
+        return
+    }
+
+    for level, sampling := range config.Sampling {
+        if !level.Legal() {
+            err = fmt.Errorf("Log level of sampling is not legal: %d", level)
             return
         }
-    } else {
-        if len(config.RotatePattern) > 0 {
-            err = errors.New("Rotate pattern does not match rotate value.")
+        if sampling.First < 0 || sampling.Thereafter < 0 || sampling.Tick < 0 {
+            err = fmt.Errorf("Sampling of %s could not be negative.", level)
             return
         }
     }
@@ -308,8 +451,27 @@ func New(w io.Writer, config Config, handle ...Handle) (logger *Logger, err erro
     }
 
     logger.Config   = config
-    logger.w        = w
-    logger.jobs     = make(chan Message, maxJobs)
+    logger.jobs     = make(chan Message, config.QueueSize)
+    logger.ctrl     = make(chan func())
+    logger.wg       = newWaitGroup()
+    logger.primary  = primary
+    logger.state    = &state{
+        level:      int32(config.Level),
+        sinkLevel:  int32(FATAL + 1),
+        quit:       make(chan struct{}),
+        abort:      make(chan struct{}),
+        done:       make(chan struct{}),
+        samples:    make(map[LevelType]*sampleCounter),
+        counters:   new(loggerCounters),
+    }
+    logger.state.counters.sinks.Store([]*sinkCounters{primary.counters})
+
+    logger.state.root = logger
+
+    err = primary.attach(logger)
+    if err != nil {
+        return
+    }
 
     logger.start()
 
@@ -317,171 +479,319 @@ func New(w io.Writer, config Config, handle ...Handle) (logger *Logger, err erro
 }
 
 
-// Start to receive logging jobs, then write log message to io.Writer. This function will rotate file log as needed.
+// Start to receive logging jobs and other functions which should be run in the log writing goroutine. The numbers of dropped messages, repeated messages if Config.Dedup is true, and write errors of sinks are reported periodically.
 func (this *Logger) start() {
-    go func() {
-        var lastMsgTime time.Time
-        for msg := range this.jobs {
-
-            // Call user defined function as needed.
-            if this.handle != nil && msg.Level >= this.handle.Level {
-                this.wg.Add(1)
-                go func(m Message) {
-                    defer this.wg.Done()
-                    (*this.handle).Func(m)
-                }(msg)
-            }
+    interval := dropReportInterval
 
-            // Check if need to rotate file log
-            if this.Rotate > R_NONE && !lastMsgTime.IsZero() {
-                timestr := this.ifRotate(lastMsgTime, msg.Time)
+    go func() {
 
-                // now rotate
-                if timestr != "" {
-                    file, _ := this.w.(*os.File)
-                    filename := file.Name()
+        // Write errors of sinks could happen with any config, so the ticker always runs.
+        ticker := time.NewTicker(interval)
+        defer ticker.Stop()
 
-                    err := file.Close()
-                    if err != nil {
-                        fmt.Fprintln(os.Stderr, err)
-                    }
+        for {
+            select {
+                case msg := <-this.jobs:
+                    this.process(msg)
 
-                    newFilename := this.rotateName(filename, timestr)
+                case f := <-this.ctrl:
+                    f()
 
-                    err = os.Rename(filename, newFilename)
-                    if err != nil {
-                        fmt.Fprintln(os.Stderr, err)
-                    }
+                case <-ticker.C:
+                    this.reportRepeated()
+                    this.state.last = nil
+                    this.reportDropped()
+                    this.reportWriteErrors()
 
-                    this.w, err = OpenFile(filename)
-                    if err != nil {
-                        fmt.Fprintln(os.Stderr, err)
-                    }
-                }
+                case <-this.state.quit:
+                    this.shutdown()
+                    return
             }
-
-            lastMsgTime = msg.Time
-            this.w.Write(this.msg2bytes(msg))
-            this.wg.Done()
         }
     }()
 }
 
 
-/* Check if need to rotate file log. If yes, return value is a time string used in filename. If not, return a empty string.
+// Write a message from the job queue, or drop it if Close() has timed out. Secrets in messages are masked by Config.Redact, and messages may be collapsed or dropped by Config.Dedup and Config.Sampling.
+func (this *Logger) process(msg Message) {
+    select {
+        case <-this.state.abort:
+            this.drop()
+        default:
+            if this.Redact != nil {
+                msg = this.Redact.redact(msg)
+            }
+            if this.dedup(msg) {
+                this.wg.Done()
+                return
+            }
+            if !this.sample(msg) {
+                this.drop()
+                return
+            }
+            if this.Dedup {
+                this.state.last = &msg
+            }
+            this.write(msg)
+    }
+}
 
-Parameters:
-    last        last log message's time.
-    current     current log message's time.
-*/
-func (this *Logger) ifRotate(last, current time.Time) string {
 
-    if this.Rotate == R_NONE {
-        return ""
+// Write a WARN message of how many messages are dropped since last report, must be called in the log writing goroutine.
+func (this *Logger) reportDropped() {
+    n := atomic.SwapUint64(&this.state.dropped, 0)
+    if n == 0 {
+        return
     }
+    this.wg.Add(1)
+    this.write(newMsg(fmt.Sprintf("log: dropped %d messages", n), WARN))
+}
 
-    var format string
 
-    switch this.Rotate {
-        case R_HOURLY:
-            format = "2006-01-02_15"
-        case R_DAILY:
-            format = "2006-01-02"
-        case R_MONTHLY:
-            format = "2006-01"
+// Process a log message: call user defined function, then write the message to sinks whose level accepts it.
+func (this *Logger) write(msg Message) {
+
+    // Call user defined function as needed.
+    if this.handle != nil && msg.Level >= this.handle.Level {
+        this.wg.Add(1)
+        go func(m Message) {
+            defer this.wg.Done()
+            (*this.handle).Func(m)
+        }(msg)
     }
 
-    lastTime := last.Format(format)
-    currentTime := current.Format(format)
+    if msg.Level >= this.state.levelOf(msg.Name) {
+        this.writeSink(0, this.primary, this.primary.counters, msg)
+    }
 
-    if lastTime != currentTime {
-        return lastTime
+    for i, entry := range this.state.sinks {
+        if msg.Level >= entry.level {
+            this.writeSink(i + 1, entry.sink, entry.counters, msg)
+        }
     }
 
-    return ""
+    this.wg.Done()
+}
+
+
+// Check if a message of the level will be written to any sink.
+func (this *Logger) enabled(level LevelType) bool {
+    return level >= this.GetLevel() || level >= LevelType(atomic.LoadInt32(&this.state.sinkLevel))
 }
 
 
-/* Generate a filename (include path) for file log rotate.
+/* Change the log level at runtime. It's safe to be called while other goroutines are logging. The level is shared by the logger and its children created by With().
 
-Parameters:
-    filename    filename of current log
-    timestr     time string that will be added to filename
+If the logger is named by Get(), the level of the name is changed, as SetLevels() does for one name. Otherwise the level of all loggers without their own levels is changed.
+
+Config.Level is the initial level, and will not change after SetLevel() is called.
 */
-func (this *Logger) rotateName(filename, timestr string) string {
+func (this *Logger) SetLevel(level LevelType) error {
+    if !level.Legal() {
+        return fmt.Errorf("Log level is not legal: %d", level)
+    }
+    if this.name != "" {
+        this.state.setNameLevel(this.name, level)
+        return nil
+    }
+    atomic.StoreInt32(&this.state.level, int32(level))
+    return nil
+}
 
-    basename := filepath.Base(filename)
 
-    idx := strings.LastIndex(basename, ".")
+// Get the current log level. For a named logger, it's the level of the name or the nearest parent name, see Get().
+func (this *Logger) GetLevel() LevelType {
+    return this.state.levelOf(this.name)
+}
 
-    var base, ext string
 
-    if idx >= 0 {
-        base = basename[:idx]
-        ext = basename[idx:]
-    } else {
-        base = basename
+// Convert a message to bytes by the config given to New().
+func (this *Logger) msg2bytes(m Message) []byte {
+    return this.primary.msg2bytes(m)
+}
+
+
+/* Send a message to the log writing goroutine. Fields of the logger are added to the message. If the job queue is full, the message may be dropped as Config.Overflow. If the logger is closed, ErrClosed is returned.
+
+closeMu is held only until the message is counted by wg, not while waiting for the queue, so Close() is not blocked by a stalled sink. Close() waits for counted messages, and a sender still waiting when Close() times out drops its message.
+*/
+func (this *Logger) send(m Message) error {
+    this.state.closeMu.RLock()
+    if atomic.LoadInt32(&this.state.closed) != 0 {
+        this.state.closeMu.RUnlock()
+        return ErrClosed
+    }
+    this.wg.Add(1)
+    this.state.closeMu.RUnlock()
+
+    if m.Level.Legal() {
+        atomic.AddUint64(&this.state.counters.accepted[m.Level], 1)
+    }
+
+    m.Name = this.name
+    m.Fields = joinFields(this.fields, m.Fields)
+
+    // The caller may be set already, e.g. by SlogHandler.
+    withStack := this.Stack && m.Level >= this.StackLevel
+    if (this.Caller && m.Caller.File == "") || withStack {
+        caller, stack := getCaller(withStack)
+        if this.Caller && m.Caller.File == "" {
+            m.Caller = caller
+        }
+        m.Stack = stack
     }
 
-    replacer := strings.NewReplacer("{time}", timestr, "{basename}", base, "{ext}", ext)
-    return filepath.Join(filepath.Dir(filename), replacer.Replace(this.RotatePattern))
+    policy := this.Overflow
+    if policy == OF_DROP_BELOW && m.Level >= this.OverflowLevel {
+        policy = OF_BLOCK
+    }
+
+    switch policy {
+        case OF_BLOCK:
+            select {
+                case this.jobs <- m:
+                case <-this.state.abort:
+                    this.drop()
+                    return ErrClosed
+            }
+
+        case OF_DROP_OLDEST:
+            for {
+                select {
+                    case this.jobs <- m:
+                        return nil
+                    default:
+                }
+
+                // The queue is full, drop the oldest one. The log writing goroutine may take it first, then try again.
+                select {
+                    case <-this.jobs:
+                        this.drop()
+                    default:
+                }
+            }
+
+        default:
+            select {
+                case this.jobs <- m:
+                default:
+                    this.drop()
+            }
+    }
+
+    return nil
 }
 
 
-func (this *Logger) msg2bytes(m Message) []byte {
+// Count a dropped message.
+func (this *Logger) drop() {
+    atomic.AddUint64(&this.state.dropped, 1)
+    atomic.AddUint64(&this.state.counters.dropped, 1)
+    this.wg.Done()
+}
+
+
+// Report an error of the logger itself as an ERROR message. It never blocks, so it's safe to be called in the log writing goroutine. If the job queue is full or the logger is closed, the error will be written to stderr.
+func (this *Logger) report(err error) {
+    m := newMsg("log: " + err.Error(), ERROR)
+    m.Fields = this.fields
 
-    if this.Utc {
-        m.Time = m.Time.UTC()
+    // Not to use closeMu here, or the log writing goroutine may wait for Close() which waits for blocked senders.
+    if atomic.LoadInt32(&this.state.closed) != 0 {
+        fmt.Fprintln(os.Stderr, m.Msg)
+        return
+    }
+
+    this.wg.Add(1)
+    select {
+        case this.jobs <- m:
+        default:
+            this.wg.Done()
+            fmt.Fprintln(os.Stderr, m.Msg)
     }
+}
 
-    replacer := strings.NewReplacer("{time}", m.Time.Format(this.TimeFormat), 
-                    "{level}", m.Level.String(),
-                    "{msg}", m.Msg)
-    s := replacer.Replace(this.LayoutStyle)
 
-    var b []byte
-    if len(s) > 0 && s[len(s)-1] != '\n' {
-        b = []byte(s + "\n")
+// Report an error of a sink by the logger which the sink is added to. If the sink is not added to any logger, write the error to stderr.
+func reportTo(logger *Logger, err error) {
+    if logger != nil {
+        logger.report(err)
     } else {
-        b = []byte(s)
+        fmt.Fprintln(os.Stderr, "log: " + err.Error())
     }
-
-    return b
 }
 
 
 // implement for io.Writer
+// Write b as an INFO message, a trailing newline is removed. Use Writer() for other levels, or to write a message per line.
 func (this *Logger) Write(b []byte) (int, error) {
-    m := newMsg(string(b), INFO)
-    this.wg.Add(1)
-    this.jobs <- m
-    return len(m.Msg), nil
+    if !this.enabled(INFO) {
+        return len(b), nil
+    }
+    m := newMsg(strings.TrimSuffix(strings.TrimSuffix(string(b), "\n"), "\r"), INFO)
+    if err := this.send(m); err != nil {
+        return 0, err
+    }
+    return len(b), nil
 }
 
 
 func (this *Logger) Print(level LevelType, v ...interface{}) (int, error) {
-    if level < this.Level {
+    if !this.enabled(level) {
         return 0, nil
     }
     m := newMsg(fmt.Sprint(v...), level)
-    this.wg.Add(1)
-    this.jobs <- m
+    if err := this.send(m); err != nil {
+        return 0, err
+    }
     return len(m.Msg), nil
 }
 
 
 func (this *Logger) Printf(level LevelType, format string, v ...interface{}) (int, error) {
-    if level < this.Level {
+    if !this.enabled(level) {
         return 0, nil
     }
     m := newMsg(fmt.Sprintf(format, v...), level)
-    this.wg.Add(1)
-    this.jobs <- m
+    if err := this.send(m); err != nil {
+        return 0, err
+    }
     return len(m.Msg), nil
 }
 
 
-// Wait termination of the log writing goroutine.
+/* Output a message with key/value pairs. See With() for the format of kv.
+
+Example:
+    logger.Printw(INFO, "user login", "user", 1001, "ip", "10.0.0.1")
+*/
+func (this *Logger) Printw(level LevelType, msg string, kv ...interface{}) (int, error) {
+    if !this.enabled(level) {
+        return 0, nil
+    }
+    m := newMsg(msg, level)
+    m.Fields = toFields(kv...)
+    if err := this.send(m); err != nil {
+        return 0, err
+    }
+    return len(m.Msg), nil
+}
+
+
+/* Create a child logger, which adds the key/value pairs to every message it outputs. The child logger shares the writer, the user defined function and the writing goroutine with its parent.
+
+Each element of kv can be a Field, or a key followed by its value:
+    reqLogger := logger.With("request_id", id, F("user", 1001))
+    reqLogger.Info("start")
+*/
+func (this *Logger) With(kv ...interface{}) *Logger {
+    child := *this
+    child.fields = joinFields(this.fields, toFields(kv...))
+    return &child
+}
+
+
+// Wait until all log messages are written, user defined functions are finished, and rotated files are compressed and cleaned up.
 func (this *Logger) Wait() {
     this.wg.Wait()
 }
@@ -555,6 +865,42 @@ func (this *Logger) Fatalf(format string, v ...interface{}) {
 }
 
 
+// --------------------------------------------
+// 6 convenient methods to output log message with key/value pairs.
+
+
+func (this *Logger) Debugw(msg string, kv ...interface{}) {
+    this.Printw(DEBUG, msg, kv...)
+}
+
+
+func (this *Logger) Noticew(msg string, kv ...interface{}) {
+    this.Printw(NOTICE, msg, kv...)
+}
+
+
+func (this *Logger) Infow(msg string, kv ...interface{}) {
+    this.Printw(INFO, msg, kv...)
+}
+
+
+func (this *Logger) Warnw(msg string, kv ...interface{}) {
+    this.Printw(WARN, msg, kv...)
+}
+
+
+func (this *Logger) Errorw(msg string, kv ...interface{}) {
+    this.Printw(ERROR, msg, kv...)
+}
+
+
+func (this *Logger) Fatalw(msg string, kv ...interface{}) {
+    this.Printw(FATAL, msg, kv...)
+    this.Wait()
+    os.Exit(1)
+}
+
+
 // --------------------------------------------
 // stdLogger
 
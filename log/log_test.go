@@ -319,3 +319,27 @@ func BenchmarkMsg2bytes(b *testing.B) {
             logger.msg2bytes(m)
         }
 }
+
+
+func ExampleLogger_With() {
+
+    var config Config
+    config.Layout      = LY_LEVEL
+    config.LayoutStyle = "{level}: {msg} {fields}"
+    config.Level       = INFO
+
+    logger, err := New(os.Stdout, config)
+    if err != nil {
+        fmt.Println(err)
+        os.Exit(1)
+    }
+
+    reqLogger := logger.With("request_id", "a1b2", F("user", 1001))
+    reqLogger.Infow("user login", "ip", "10.0.0.1")
+    reqLogger.Errorw("query failed", "error", "no such table", "latency", 3*time.Millisecond)
+    logger.Info("no fields")
+    logger.Wait()
+    // Output: INFO: user login request_id=a1b2 user=1001 ip=10.0.0.1
+    // ERROR: query failed request_id=a1b2 user=1001 error="no such table" latency=3ms
+    // INFO: no fields
+}
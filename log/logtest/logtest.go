@@ -0,0 +1,193 @@
+/* Package logtest helps to test code which writes logs by log.Logger.
+
+Messages are recorded by a Recorder. Methods of Recorder wait for the logger to write all messages sent, so there is no need to call Logger.Wait() before checking.
+
+Example:
+    func TestLogin(t *testing.T) {
+        logger, rec := logtest.New(t)
+
+        server := NewServer(logger)
+        server.Login("bob", "wrong password")
+
+        rec.AssertContains(log.WARN, `login failed`)
+    }
+*/
+package logtest
+
+import "context"
+import "io"
+import "io/ioutil"
+import "regexp"
+import "strings"
+import "sync"
+import "testing"
+import "github.com/m3ng9i/go-utils/log"
+
+
+// ------------------------------------------------
+// Recorder
+
+
+// A sink records messages, and checks them in tests.
+type Recorder struct {
+    t testing.TB
+    logger *log.Logger
+    mu sync.Mutex
+    messages []log.Message
+}
+
+
+// Write log output by t.Log, so it's shown with the test's output.
+type tWriter struct {
+    t testing.TB
+}
+
+
+func (this *tWriter) Write(b []byte) (int, error) {
+    this.t.Log(strings.TrimSuffix(string(b), "\n"))
+    return len(b), nil
+}
+
+
+/* Create a logger of DEBUG level for a test, and a Recorder which records all its messages. The output of the logger is written by t.Log, unless quiet is true. The logger is closed when the test finishes.
+*/
+func New(t testing.TB, quiet ...bool) (*log.Logger, *Recorder) {
+    t.Helper()
+
+    var w io.Writer = &tWriter{t: t}
+    if len(quiet) > 0 && quiet[0] {
+        w = ioutil.Discard
+    }
+
+    var config log.Config
+    config.Level       = log.DEBUG
+    config.Layout      = log.LY_LEVEL
+    config.LayoutStyle = "{level} {name} {msg} {fields}"
+
+    logger, err := log.New(w, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // The output is written by t.Log, which could not be called after the test finishes.
+    t.Cleanup(func() {
+        logger.Close(context.Background())
+    })
+
+    return logger, NewRecorder(t, logger)
+}
+
+
+// Create a Recorder which records messages of all levels of an existing logger.
+func NewRecorder(t testing.TB, logger *log.Logger) *Recorder {
+    t.Helper()
+
+    rec := &Recorder{t: t, logger: logger}
+    if err := logger.AddSink(rec, log.DEBUG); err != nil {
+        t.Fatal(err)
+    }
+    return rec
+}
+
+
+func (this *Recorder) WriteMessage(m log.Message) error {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+
+    this.messages = append(this.messages, m)
+    return nil
+}
+
+
+// Get recorded messages, after all messages sent to the logger are written.
+func (this *Recorder) Messages() []log.Message {
+    this.logger.Wait()
+
+    this.mu.Lock()
+    defer this.mu.Unlock()
+
+    return append([]log.Message(nil), this.messages...)
+}
+
+
+// Remove recorded messages.
+func (this *Recorder) Reset() {
+    this.logger.Wait()
+
+    this.mu.Lock()
+    defer this.mu.Unlock()
+
+    this.messages = nil
+}
+
+
+// Find messages of a level whose msg matches a regexp. An illegal regexp fails the test.
+func (this *Recorder) Find(level log.LevelType, pattern string) []log.Message {
+    this.t.Helper()
+
+    re, err := regexp.Compile(pattern)
+    if err != nil {
+        this.t.Fatal(err)
+        return nil
+    }
+
+    var found []log.Message
+    for _, m := range this.Messages() {
+        if m.Level == level && re.MatchString(m.Msg) {
+            found = append(found, m)
+        }
+    }
+    return found
+}
+
+
+// Check if there's a message of a level whose msg matches a regexp.
+func (this *Recorder) Contains(level log.LevelType, pattern string) bool {
+    this.t.Helper()
+    return len(this.Find(level, pattern)) > 0
+}
+
+
+// Fail the test if there's no message of a level whose msg matches a regexp.
+func (this *Recorder) AssertContains(level log.LevelType, pattern string) {
+    this.t.Helper()
+    if !this.Contains(level, pattern) {
+        this.t.Errorf("No %s message matches %q, messages are:\n%s", level, pattern, this.String())
+    }
+}
+
+
+// Fail the test if there's a message of a level whose msg matches a regexp.
+func (this *Recorder) AssertNotContains(level log.LevelType, pattern string) {
+    this.t.Helper()
+    if found := this.Find(level, pattern); len(found) > 0 {
+        this.t.Errorf("Unexpected %s message matches %q: %s", level, pattern, found[0].Msg)
+    }
+}
+
+
+// Fail the test if the number of messages of a level is not n.
+func (this *Recorder) AssertCount(level log.LevelType, n int) {
+    this.t.Helper()
+    if count := len(this.Find(level, "")); count != n {
+        this.t.Errorf("Expect %d %s messages, got %d, messages are:\n%s", n, level, count, this.String())
+    }
+}
+
+
+// Format recorded messages one per line, like "WARN msg key=value".
+func (this *Recorder) String() string {
+    var b strings.Builder
+    for _, m := range this.Messages() {
+        b.WriteString(m.Level.String())
+        if m.Name != "" {
+            b.WriteString(" " + m.Name)
+        }
+        b.WriteString(" " + m.Msg)
+        if len(m.Fields) > 0 {
+            b.WriteString(" " + m.Fields.String())
+        }
+        b.WriteByte('\n')
+    }
+    return b.String()
+}
@@ -0,0 +1,65 @@
+package logtest
+
+import "testing"
+import "fmt"
+import "github.com/m3ng9i/go-utils/log"
+
+
+// A testing.TB records failures instead of failing the test.
+type fakeT struct {
+    testing.TB
+    errors []string
+}
+
+func (this *fakeT) Helper() {}
+
+func (this *fakeT) Errorf(format string, args ...interface{}) {
+    this.errors = append(this.errors, fmt.Sprintf(format, args...))
+}
+
+
+func TestRecorder(t *testing.T) {
+
+    logger, rec := New(t)
+
+    logger.Info("server started")
+    logger.Get("db").Warnw("slow query", "ms", 1200)
+    logger.Warn("disk is almost full")
+
+    rec.AssertContains(log.WARN, `^slow`)
+    rec.AssertNotContains(log.ERROR, ``)
+    rec.AssertCount(log.WARN, 2)
+
+    if found := rec.Find(log.WARN, "query"); len(found) != 1 || found[0].Name != "db" || found[0].Fields.String() != "ms=1200" {
+        t.Errorf("Unexpected messages: %v", found)
+    }
+
+    expected := "INFO server started\nWARN db slow query ms=1200\nWARN disk is almost full\n"
+    if rec.String() != expected {
+        t.Errorf("Unexpected messages: %q", rec.String())
+    }
+
+    rec.Reset()
+    if len(rec.Messages()) != 0 {
+        t.Error("Messages should be removed.")
+    }
+}
+
+
+func TestRecorderFailure(t *testing.T) {
+
+    logger, _ := New(t, true)
+
+    ft := &fakeT{TB: t}
+    rec := NewRecorder(ft, logger)
+
+    logger.Error("connection refused")
+
+    rec.AssertContains(log.WARN, "connection")
+    rec.AssertNotContains(log.ERROR, "refused")
+    rec.AssertCount(log.ERROR, 2)
+
+    if len(ft.errors) != 3 {
+        t.Errorf("Unexpected failures: %q", ft.errors)
+    }
+}
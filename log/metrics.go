@@ -0,0 +1,210 @@
+package log
+
+import "fmt"
+import "net/http"
+import "sync/atomic"
+
+
+// ------------------------------------------------
+// Stats
+
+
+// Counters of a logger, accessed atomically. It's allocated alone and the 64-bit counters are its first fields, so they are 64-bit aligned on 32-bit platforms, as state.dropped is.
+type loggerCounters struct {
+    accepted [FATAL + 1]uint64  // Messages accepted per level.
+    dropped uint64              // Messages dropped since the logger is created.
+    sinks atomic.Value          // Counters of sinks, a []*sinkCounters with the primary sink first, which is replaced rather than changed.
+}
+
+
+// Counters of a sink, accessed atomically. It's allocated alone, the 64-bit counters are aligned as loggerCounters.
+type sinkCounters struct {
+    messages uint64
+    bytes uint64
+    rotations uint64
+    errors uint64
+
+    // Fields below are only accessed in the log writing goroutine.
+    failing bool        // If the last write failed.
+    unreported uint64   // Number of write errors not reported yet.
+    lastErr error       // The last write error.
+}
+
+
+// Get the counters of a sink. A WriterSink counts bytes and rotations by itself, so its own counters are used.
+func newSinkCounters(sink Sink) *sinkCounters {
+    if s, ok := sink.(*WriterSink); ok {
+        return s.counters
+    }
+    return new(sinkCounters)
+}
+
+
+// Add counters of a sink added by AddSink(), must be called in the log writing goroutine.
+func (this *loggerCounters) addSink(c *sinkCounters) {
+    old := this.sinks.Load().([]*sinkCounters)
+    sinks := make([]*sinkCounters, len(old), len(old) + 1)
+    copy(sinks, old)
+    this.sinks.Store(append(sinks, c))
+}
+
+
+// Counters of a sink, see Stats.
+type SinkStats struct {
+    Messages    uint64  // Messages written successfully.
+    Bytes       uint64  // Bytes written, counted by WriterSink only.
+    Rotations   uint64  // Log files rotated, counted by WriterSink only.
+    WriteErrors uint64  // Messages failed to be written.
+}
+
+
+/* Counters of a logger since it's created, see Logger.Stats().
+
+Messages written by the logger itself, e.g. errors of sinks and reports of dropped messages, are not counted as accepted, but they are counted by sinks.
+*/
+type Stats struct {
+    Accepted    map[LevelType]uint64    // Messages accepted per level, i.e. not filtered out by levels. They may be dropped later.
+    Dropped     uint64                  // Messages dropped by Config.Overflow, Config.Sampling, or Close() timing out.
+    Bytes       uint64                  // Sum of SinkStats.Bytes of all sinks.
+    Rotations   uint64                  // Sum of SinkStats.Rotations of all sinks.
+    WriteErrors uint64                  // Sum of SinkStats.WriteErrors of all sinks.
+    Sinks       []SinkStats             // Counters of each sink. The primary sink is the first, followed by sinks in the order they are added.
+}
+
+
+/* Get counters of the logger. They are shared by the logger and its children created by With() and Get().
+
+Example:
+    stats := logger.Stats()
+    fmt.Println(stats.Accepted[log.ERROR], stats.WriteErrors)
+*/
+func (this *Logger) Stats() Stats {
+
+    counters := this.state.counters
+
+    stats := Stats{
+        Accepted:   make(map[LevelType]uint64),
+        Dropped:    atomic.LoadUint64(&counters.dropped),
+    }
+
+    for level := DEBUG; level <= FATAL; level++ {
+        stats.Accepted[level] = atomic.LoadUint64(&counters.accepted[level])
+    }
+
+    for _, c := range counters.sinks.Load().([]*sinkCounters) {
+        s := SinkStats{
+            Messages:       atomic.LoadUint64(&c.messages),
+            Bytes:          atomic.LoadUint64(&c.bytes),
+            Rotations:      atomic.LoadUint64(&c.rotations),
+            WriteErrors:    atomic.LoadUint64(&c.errors),
+        }
+        stats.Bytes += s.Bytes
+        stats.Rotations += s.Rotations
+        stats.WriteErrors += s.WriteErrors
+        stats.Sinks = append(stats.Sinks, s)
+    }
+
+    return stats
+}
+
+
+/* Write a message to a sink and count it.
+
+The first error of a sink is reported at once. The following ones are counted and reported periodically by reportWriteErrors(), and when the sink recovers, so a broken sink does not flood other sinks but is never silent.
+*/
+func (this *Logger) writeSink(index int, sink Sink, c *sinkCounters, msg Message) {
+
+    err := sink.WriteMessage(msg)
+    if err == nil {
+        atomic.AddUint64(&c.messages, 1)
+        if c.failing {
+            c.failing = false
+            this.reportWriteError(index, c)
+        }
+        return
+    }
+
+    atomic.AddUint64(&c.errors, 1)
+    if !c.failing {
+        c.failing = true
+        this.report(fmt.Errorf("Writing to sink %d failed: %v", index, err))
+        return
+    }
+    c.unreported++
+    c.lastErr = err
+}
+
+
+// Report the write errors of a sink which are not reported yet, must be called in the log writing goroutine.
+func (this *Logger) reportWriteError(index int, c *sinkCounters) {
+    if c.unreported == 0 {
+        return
+    }
+    this.report(fmt.Errorf("Writing to sink %d failed %d more times, the last error: %v", index, c.unreported, c.lastErr))
+    c.unreported = 0
+    c.lastErr = nil
+}
+
+
+// Report the write errors of all sinks which are not reported yet, must be called in the log writing goroutine.
+func (this *Logger) reportWriteErrors() {
+    for i, c := range this.state.counters.sinks.Load().([]*sinkCounters) {
+        this.reportWriteError(i, c)
+    }
+}
+
+
+/* Create an http.Handler which responds with the logger's counters in Prometheus text exposition format. Sinks are labeled by their indexes in Stats.Sinks, the primary sink is "0".
+
+Example:
+    http.Handle("/metrics/log", logger.MetricsHandler())
+
+Output:
+    # HELP log_messages_total Messages accepted by the logger.
+    # TYPE log_messages_total counter
+    log_messages_total{level="DEBUG"} 0
+    log_messages_total{level="INFO"} 12
+    ...
+    log_sink_bytes_total{sink="0"} 1024
+*/
+func (this *Logger) MetricsHandler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+        if r.Method != "GET" && r.Method != "HEAD" {
+            w.Header().Set("Allow", "GET, HEAD")
+            http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+
+        stats := this.Stats()
+
+        w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+
+        header := func(name, help string) {
+            fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", name, help, name)
+        }
+
+        header("log_messages_total", "Messages accepted by the logger.")
+        for level := DEBUG; level <= FATAL; level++ {
+            fmt.Fprintf(w, "log_messages_total{level=%q} %d\n", level.String(), stats.Accepted[level])
+        }
+
+        header("log_dropped_messages_total", "Messages dropped by the logger.")
+        fmt.Fprintf(w, "log_dropped_messages_total %d\n", stats.Dropped)
+
+        for _, metric := range []struct {
+            name, help string
+            value func(SinkStats) uint64
+        }{
+            {"log_sink_messages_total", "Messages written to the sink.", func(s SinkStats) uint64 { return s.Messages }},
+            {"log_sink_bytes_total", "Bytes written to the sink.", func(s SinkStats) uint64 { return s.Bytes }},
+            {"log_sink_rotations_total", "Log files rotated by the sink.", func(s SinkStats) uint64 { return s.Rotations }},
+            {"log_sink_write_errors_total", "Messages failed to be written to the sink.", func(s SinkStats) uint64 { return s.WriteErrors }},
+        } {
+            header(metric.name, metric.help)
+            for i, s := range stats.Sinks {
+                fmt.Fprintf(w, "%s{sink=\"%d\"} %d\n", metric.name, i, metric.value(s))
+            }
+        }
+    })
+}
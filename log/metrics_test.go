@@ -0,0 +1,205 @@
+package log
+
+import "testing"
+import "bytes"
+import "context"
+import "errors"
+import "net/http/httptest"
+import "os"
+import "strings"
+import "sync"
+import "time"
+
+
+// A writer which fails while broken is true.
+type brokenWriter struct {
+    sync.Mutex
+    broken bool
+    buf bytes.Buffer
+}
+
+
+func (this *brokenWriter) Write(b []byte) (int, error) {
+    this.Lock()
+    defer this.Unlock()
+    if this.broken {
+        return 0, errors.New("broken")
+    }
+    return this.buf.Write(b)
+}
+
+
+func (this *brokenWriter) String() string {
+    this.Lock()
+    defer this.Unlock()
+    return this.buf.String()
+}
+
+
+func (this *brokenWriter) set(broken bool) {
+    this.Lock()
+    this.broken = broken
+    this.Unlock()
+}
+
+
+func TestStats(t *testing.T) {
+    var buf bytes.Buffer
+
+    logger, err := New(&buf, Config{Layout: LY_MSGONLY, LayoutStyle: "{msg}", Level: INFO,
+        Sampling: map[LevelType]Sampling{DEBUG: {First: 1}}})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    w := new(brokenWriter)
+    if err = logger.AddWriter(w, Config{Layout: LY_MSGONLY, LayoutStyle: "{msg}", Level: DEBUG}); err != nil {
+        t.Fatal(err)
+    }
+
+    logger.Info("info")
+    logger.Get("db").Error("error")
+    logger.Debug("debug1")
+    logger.Debug("debug2")      // dropped by sampling
+    logger.Wait()
+
+    w.set(true)
+    logger.Info("lost1")
+    logger.Info("lost2")
+    logger.Wait()
+    w.set(false)
+    logger.Wait()
+
+    stats := logger.Stats()
+
+    if stats.Accepted[INFO] != 3 || stats.Accepted[ERROR] != 1 || stats.Accepted[DEBUG] != 2 || stats.Accepted[WARN] != 0 {
+        t.Errorf("Unexpected accepted messages: %v", stats.Accepted)
+    }
+    if stats.Dropped != 1 {
+        t.Errorf("Expected 1 dropped message, got %d", stats.Dropped)
+    }
+    if len(stats.Sinks) != 2 {
+        t.Fatalf("Expected 2 sinks, got %d", len(stats.Sinks))
+    }
+
+    // The primary sink gets 4 messages and the report of the broken sink.
+    if s := stats.Sinks[0]; s.Messages != 5 || s.Bytes != uint64(buf.Len()) || s.WriteErrors != 0 {
+        t.Errorf("Unexpected stats of primary sink: %+v", s)
+    }
+    if !strings.Contains(buf.String(), "log: Writing to sink 1 failed: broken") || strings.Count(buf.String(), "failed") != 1 {
+        t.Errorf("The write error should be reported once: %q", buf.String())
+    }
+
+    // The sink missed the report of its own error.
+    if s := stats.Sinks[1]; s.Messages != 3 || s.WriteErrors != 3 || s.Bytes != uint64(w.buf.Len()) {
+        t.Errorf("Unexpected stats of sink: %+v", s)
+    }
+    if stats.WriteErrors != 3 || stats.Bytes != uint64(buf.Len() + w.buf.Len()) {
+        t.Errorf("Unexpected stats: %+v", stats)
+    }
+}
+
+
+func TestStatsRotations(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+
+    logger, err := New(file, Config{Layout: LY_MSGONLY, LayoutStyle: "{msg}", MaxSize: 10})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for _, s := range []string{"line1", "line2", "line3"} {
+        logger.Info(s)
+    }
+    logger.Wait()
+
+    if stats := logger.Stats(); stats.Rotations != 2 || stats.Bytes != 18 {
+        t.Errorf("Unexpected stats: %+v", stats)
+    }
+}
+
+
+func TestMetricsHandler(t *testing.T) {
+
+    logger, err := New(&bytes.Buffer{}, Config{Level: DEBUG})
+    if err != nil {
+        t.Fatal(err)
+    }
+    logger.Error("error")
+    logger.Wait()
+
+    w := httptest.NewRecorder()
+    logger.MetricsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+    if !strings.HasPrefix(w.Header().Get("Content-Type"), "text/plain") {
+        t.Errorf("Unexpected content type: %s", w.Header().Get("Content-Type"))
+    }
+
+    body := w.Body.String()
+    for _, line := range []string{
+        "# TYPE log_messages_total counter\n",
+        `log_messages_total{level="ERROR"} 1` + "\n",
+        `log_messages_total{level="DEBUG"} 0` + "\n",
+        "log_dropped_messages_total 0\n",
+        `log_sink_messages_total{sink="0"} 1` + "\n",
+        `log_sink_write_errors_total{sink="0"} 0` + "\n",
+    } {
+        if !strings.Contains(body, line) {
+            t.Errorf("%q is not found in:\n%s", line, body)
+        }
+    }
+
+    w = httptest.NewRecorder()
+    logger.MetricsHandler().ServeHTTP(w, httptest.NewRequest("POST", "/metrics", nil))
+    if w.Code != 405 || w.Header().Get("Allow") != "GET, HEAD" {
+        t.Errorf("Unexpected response of POST: %d", w.Code)
+    }
+}
+
+
+// Write errors after the first one are reported periodically, and when the sink recovers.
+func TestReportWriteErrors(t *testing.T) {
+
+    interval := dropReportInterval
+    dropReportInterval = 20 * time.Millisecond
+    defer func() {
+        dropReportInterval = interval
+    }()
+
+    primary := new(brokenWriter)
+    logger, err := New(primary, Config{Layout: LY_MSGONLY, LayoutStyle: "{msg}", Level: ERROR})
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer logger.Close(context.Background())
+
+    w := new(brokenWriter)
+    w.set(true)
+    if err = logger.AddWriter(w, Config{Layout: LY_MSGONLY, LayoutStyle: "{msg}", Level: DEBUG}); err != nil {
+        t.Fatal(err)
+    }
+
+    logger.Info("lost1")
+    logger.Info("lost2")
+    time.Sleep(5 * dropReportInterval)
+
+    // Reports are written to the broken sink too, so they are reported in the next period.
+    s := primary.String()
+    if strings.Count(s, "log: Writing to sink 1 failed: broken\n") != 1 || !strings.Contains(s, "log: Writing to sink 1 failed 2 more times, the last error: broken\n") {
+        t.Errorf("Unexpected reports: %q", s)
+    }
+
+    w.set(false)
+    logger.Info("written")
+    logger.Wait()
+    s = primary.String()
+
+    time.Sleep(5 * dropReportInterval)
+    if primary.String() != s {
+        t.Errorf("Errors should not be reported after the sink recovers: %q", primary.String())
+    }
+    if !strings.Contains(w.String(), "written\n") {
+        t.Errorf("Unexpected output of the recovered sink: %q", w.String())
+    }
+}
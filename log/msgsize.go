@@ -0,0 +1,12 @@
+//go:build !windows && !plan9
+
+package log
+
+import "errors"
+import "syscall"
+
+
+// Check if a datagram is not sent for being too large.
+func isMessageTooLong(err error) bool {
+    return errors.Is(err, syscall.EMSGSIZE)
+}
@@ -0,0 +1,7 @@
+package log
+
+
+// Errors of plan9 are strings, datagrams too large are not detected.
+func isMessageTooLong(err error) bool {
+    return false
+}
@@ -0,0 +1,14 @@
+package log
+
+import "errors"
+import "syscall"
+
+
+// WSAEMSGSIZE, which is not defined in syscall.
+const wsaemsgsize = syscall.Errno(10040)
+
+
+// Check if a datagram is not sent for being too large.
+func isMessageTooLong(err error) bool {
+    return errors.Is(err, wsaemsgsize)
+}
@@ -0,0 +1,144 @@
+package log
+
+import "errors"
+import "fmt"
+import "strings"
+import "sync/atomic"
+
+
+// ------------------------------------------------
+// Named loggers
+
+
+/* Get a logger named name, e.g. "http.auth". It shares sinks, queue and settings with the logger created by New(), but not the fields added by With(). Getting the same name returns the same logger.
+
+The level of a named logger is set by SetLevel() or SetLevels(). If a name has no level, the level of its nearest parent name is used, e.g. "http" for "http.auth", then the level of the logger created by New(). The name is shown by the {name} mark in the layout style.
+
+Example:
+    db := logger.Get("db")
+    logger.SetLevels("http.auth=debug,db=warn,*=info")
+*/
+func (this *Logger) Get(name string) *Logger {
+    this.state.registryMu.Lock()
+    defer this.state.registryMu.Unlock()
+
+    if logger, ok := this.state.registry[name]; ok {
+        return logger
+    }
+
+    logger := new(Logger)
+    *logger = *this.state.root
+    logger.name = name
+
+    if this.state.registry == nil {
+        this.state.registry = make(map[string]*Logger)
+    }
+    this.state.registry[name] = logger
+    return logger
+}
+
+
+// Get the name of the logger, set by Get(). It's empty for the logger created by New().
+func (this *Logger) Name() string {
+    return this.name
+}
+
+
+// Get the level of a logger name. The level of the name or its nearest parent is used, otherwise the level of the logger created by New().
+func (this *state) levelOf(name string) LevelType {
+    if name != "" {
+        levels, _ := this.levels.Load().(map[string]LevelType)
+        for len(levels) > 0 {
+            if level, ok := levels[name]; ok {
+                return level
+            }
+            idx := strings.LastIndex(name, ".")
+            if idx < 0 {
+                break
+            }
+            name = name[:idx]
+        }
+    }
+    return LevelType(atomic.LoadInt32(&this.level))
+}
+
+
+// Set the level of a logger name. The map of levels is copied, so it could be read without lock.
+func (this *state) setNameLevel(name string, level LevelType) {
+    this.registryMu.Lock()
+    defer this.registryMu.Unlock()
+
+    old, _ := this.levels.Load().(map[string]LevelType)
+    levels := make(map[string]LevelType, len(old) + 1)
+    for k, v := range old {
+        levels[k] = v
+    }
+    levels[name] = level
+    this.levels.Store(levels)
+}
+
+
+/* Parse levels of logger names from a string like "http.auth=debug,db=warn,*=info". Each level is parsed by String2Level(). The name "*" means the level of the logger created by New().
+*/
+func parseLevels(spec string) (levels map[string]LevelType, def LevelType, hasDef bool, err error) {
+
+    levels = make(map[string]LevelType)
+
+    for _, item := range strings.Split(spec, ",") {
+        item = strings.TrimSpace(item)
+        if item == "" {
+            continue
+        }
+
+        idx := strings.Index(item, "=")
+        if idx < 0 {
+            err = fmt.Errorf("Level setting is not legal: %s", item)
+            return
+        }
+
+        name := strings.TrimSpace(item[:idx])
+        levelStr := strings.TrimSpace(item[idx+1:])
+
+        level, ok := String2Level(levelStr)
+        if !ok {
+            err = fmt.Errorf("Log level is not legal: %s", levelStr)
+            return
+        }
+
+        switch name {
+            case "":
+                err = errors.New("Logger name could not be empty: " + item)
+                return
+            case "*":
+                def, hasDef = level, true
+            default:
+                levels[name] = level
+        }
+    }
+
+    return
+}
+
+
+/* Set levels of named loggers from a string like "http.auth=debug,db=warn,*=info", levels of names not in the string are removed. The name "*" sets the level of the logger created by New(), which is used by names without their own levels. If the string is not legal, nothing is changed.
+
+Example:
+    // Debug "db" and its children like "db.pool", warn "http.auth", and info for the rest.
+    logger.SetLevels("db=debug,http.auth=warn,*=info")
+*/
+func (this *Logger) SetLevels(spec string) error {
+
+    levels, def, hasDef, err := parseLevels(spec)
+    if err != nil {
+        return err
+    }
+
+    this.state.registryMu.Lock()
+    defer this.state.registryMu.Unlock()
+
+    if hasDef {
+        atomic.StoreInt32(&this.state.level, int32(def))
+    }
+    this.state.levels.Store(levels)
+    return nil
+}
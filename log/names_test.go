@@ -0,0 +1,85 @@
+package log
+
+import "testing"
+import "bytes"
+
+
+func TestParseLevels(t *testing.T) {
+
+    levels, def, hasDef, err := parseLevels(" http.auth=debug, db=WARN ,*=info,")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(levels) != 2 || levels["http.auth"] != DEBUG || levels["db"] != WARN || !hasDef || def != INFO {
+        t.Errorf("Unexpected levels: %v %v %v", levels, def, hasDef)
+    }
+
+    for _, spec := range []string{"db", "db=verbose", "=debug"} {
+        if _, _, _, err = parseLevels(spec); err == nil {
+            t.Errorf("%q should not be accepted.", spec)
+        }
+    }
+}
+
+
+func TestNamedLogger(t *testing.T) {
+    var buf bytes.Buffer
+
+    logger, err := New(&buf, Config{Layout: LY_LEVEL, LayoutStyle: "{level} {name}: {msg}", Level: WARN})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    auth := logger.Get("http.auth")
+    if logger.Get("http.auth") != auth || auth.Name() != "http.auth" {
+        t.Error("Get() should return the same logger for a name.")
+    }
+
+    if err = logger.SetLevels("http=debug,http.auth=error,db=info,*=notice"); err != nil {
+        t.Fatal(err)
+    }
+    if err = logger.SetLevels("http=debug,db"); err == nil {
+        t.Error("Illegal levels should not be set.")
+    }
+
+    tests := map[string]LevelType{
+        "":                 NOTICE,
+        "http":             DEBUG,
+        "http.server":      DEBUG,
+        "http.auth":        ERROR,
+        "http.auth.jwt":    ERROR,
+        "db":               INFO,
+        "dbx":              NOTICE,
+    }
+    for name, expected := range tests {
+        l := logger
+        if name != "" {
+            l = logger.Get(name)
+        }
+        if level := l.GetLevel(); level != expected {
+            t.Errorf("%q: expect %s, got %s", name, expected, level)
+        }
+    }
+
+    logger.Get("http.server").Debug("http debug")
+    auth.Warn("auth warn")
+    auth.With("user", 1001).Error("auth error")
+    logger.Get("db").Info("db info")
+    logger.Info("root info")
+    logger.Debug("root debug")
+
+    // The level of a name is changed by SetLevel() of the named logger.
+    logger.Wait()
+    logger.Get("db").SetLevel(ERROR)
+    logger.Get("db.pool").Warn("db warn")
+
+    logger.Wait()
+
+    expected := "DEBUG http.server: http debug\n" +
+        "ERROR http.auth: auth error\n" +
+        "INFO db: db info\n" +
+        "INFO: root info\n"
+    if buf.String() != expected {
+        t.Errorf("Unexpected output: %q", buf.String())
+    }
+}
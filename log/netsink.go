@@ -0,0 +1,422 @@
+package log
+
+import "errors"
+import "fmt"
+import "net"
+import "sync"
+import "time"
+
+
+// ------------------------------------------------
+// NetConfig
+
+
+// Settings of sinks sending messages to a remote end, e.g. SyslogSink, JSONSink and HTTPSink. Zero values are set to default.
+type NetConfig struct {
+    SpoolSize   int             // Max number of messages kept while the remote end is down, the oldest ones are dropped when it's full. If zero, 1024 is used.
+    MinBackoff  time.Duration   // Wait time before the first retry after sending fails, doubled after every failure. If zero, 100ms is used.
+    MaxBackoff  time.Duration   // Max wait time between retries. If zero, 30s is used.
+    Timeout     time.Duration   // Timeout of connecting and sending, and how long Close() waits for messages to be sent. If zero, 5s is used.
+}
+
+
+func setNetConfigDefault(config *NetConfig) {
+    if config.SpoolSize == 0 {
+        config.SpoolSize = maxJobs
+    }
+    if config.MinBackoff == 0 {
+        config.MinBackoff = 100 * time.Millisecond
+    }
+    if config.MaxBackoff == 0 {
+        config.MaxBackoff = 30 * time.Second
+    }
+    if config.Timeout == 0 {
+        config.Timeout = 5 * time.Second
+    }
+}
+
+
+func isNetConfigLegal(config *NetConfig) error {
+    if config.SpoolSize < 0 || config.MinBackoff < 0 || config.MaxBackoff < 0 || config.Timeout < 0 {
+        return errors.New("Spool size, backoff and timeout could not be negative.")
+    }
+    return nil
+}
+
+
+// ------------------------------------------------
+// shipper
+
+
+/* A shipper keeps formatted messages in a bounded spool, and sends them to a remote end in a background goroutine, so the log writing goroutine is never blocked by network.
+
+If sending fails, the shipper retries with exponential backoff. Messages dropped from the spool meanwhile are reported after the remote end is back. Messages the remote end never accepts are dropped and reported at once, so they don't block messages behind them.
+*/
+type shipper struct {
+    NetConfig
+    batchSize int                           // Max number of messages sent at once.
+    flushInterval time.Duration             // How long to wait for a full batch. If zero, messages are sent at once.
+    send func(batch [][]byte) (int, error)  // Send messages, return the number of messages sent.
+    stop func()                             // Called when the background goroutine exits, e.g. to close the connection.
+    logger *Logger                          // The logger which the sink is added to.
+
+    mu sync.Mutex
+    spool [][]byte
+    head uint64             // Sequence number of spool[0], increased when messages are sent or dropped.
+    dropped uint64          // Number of messages dropped since last report.
+    started bool            // If the background goroutine is started, i.e. the sink is added to a logger.
+    closed bool
+    notify chan struct{}    // Signaled when a message is added or the shipper is closed.
+    abort chan struct{}     // Closed when Close() times out.
+    done chan struct{}      // Closed when the background goroutine exits.
+}
+
+
+// Create a shipper. Its background goroutine is started when it's added to a logger, so a sink never added leaks no goroutine.
+func newShipper(config NetConfig, batchSize int, flushInterval time.Duration, send func([][]byte) (int, error), stop func()) *shipper {
+    this := &shipper{
+        NetConfig:      config,
+        batchSize:      batchSize,
+        flushInterval:  flushInterval,
+        send:           send,
+        stop:           stop,
+        notify:         make(chan struct{}, 1),
+        abort:          make(chan struct{}),
+        done:           make(chan struct{}),
+    }
+    return this
+}
+
+
+// Add the shipper to a logger, and start the background goroutine.
+func (this *shipper) attach(logger *Logger) error {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+
+    if this.closed {
+        return errors.New("Sink is closed.")
+    }
+    if this.logger != nil {
+        return errors.New("Sink has been added to a logger.")
+    }
+    this.logger = logger
+    this.started = true
+    go this.loop()
+    return nil
+}
+
+
+func (this *shipper) signal() {
+    select {
+        case this.notify <- struct{}{}:
+        default:
+    }
+}
+
+
+// Add a formatted message to the spool. If the spool is full, the oldest message is dropped.
+func (this *shipper) push(b []byte) error {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+
+    if this.closed {
+        return errors.New("Sink is closed.")
+    }
+
+    if len(this.spool) >= this.SpoolSize {
+        this.spool = this.spool[1:]
+        this.head++
+        this.dropped++
+    }
+    this.spool = append(this.spool, b)
+
+    this.signal()
+    return nil
+}
+
+
+// Number of messages in the spool.
+func (this *shipper) len() int {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    return len(this.spool)
+}
+
+
+// Wait until a batch is full, or flushInterval passes. Return false if the shipper is aborted.
+func (this *shipper) waitBatch() bool {
+    timer := time.NewTimer(this.flushInterval)
+    defer timer.Stop()
+
+    for {
+        this.mu.Lock()
+        full := len(this.spool) >= this.batchSize || this.closed
+        this.mu.Unlock()
+        if full {
+            return true
+        }
+
+        select {
+            case <-this.notify:
+            case <-timer.C:
+                return true
+            case <-this.abort:
+                return false
+        }
+    }
+}
+
+
+// Send messages in the spool until the shipper is closed and the spool is empty, or the shipper is aborted.
+func (this *shipper) loop() {
+
+    defer close(this.done)
+    if this.stop != nil {
+        defer this.stop()
+    }
+
+    backoff := this.MinBackoff
+    failing := false
+
+    for {
+        this.mu.Lock()
+        n, closed := len(this.spool), this.closed
+        this.mu.Unlock()
+
+        if n == 0 {
+            if closed {
+                return
+            }
+            select {
+                case <-this.notify:
+                case <-this.abort:
+                    return
+            }
+            continue
+        }
+
+        if n < this.batchSize && this.flushInterval > 0 && !closed {
+            if !this.waitBatch() {
+                return
+            }
+        }
+
+        this.mu.Lock()
+        start := this.head
+        batch := this.spool
+        if len(batch) > this.batchSize {
+            batch = batch[:this.batchSize]
+        }
+        batch = append([][]byte(nil), batch...)
+        this.mu.Unlock()
+
+        sent, err := this.send(batch)
+
+        // Rejected messages are counted in sent and dropped, and the remote end is taken as up.
+        if rejected, ok := err.(*rejectedError); ok {
+            reportTo(this.logger, fmt.Errorf("dropped %d messages rejected by the remote end: %v", rejected.n, rejected.err))
+            err = nil
+        }
+
+        this.mu.Lock()
+        // Messages may be dropped from the spool while sending.
+        if end := start + uint64(sent); end > this.head {
+            this.spool = this.spool[end - this.head:]
+            this.head = end
+        }
+        dropped := this.dropped
+        if err == nil {
+            this.dropped = 0
+        }
+        this.mu.Unlock()
+
+        if err == nil {
+            failing = false
+            backoff = this.MinBackoff
+            if dropped > 0 {
+                reportTo(this.logger, fmt.Errorf("dropped %d messages while the remote end was down", dropped))
+            }
+            continue
+        }
+
+        // Report the first failure only, the report itself is sent to the sink too.
+        if !failing {
+            failing = true
+            reportTo(this.logger, err)
+        }
+
+        select {
+            case <-time.After(backoff):
+            case <-this.abort:
+                return
+        }
+
+        backoff *= 2
+        if backoff > this.MaxBackoff {
+            backoff = this.MaxBackoff
+        }
+    }
+}
+
+
+// Stop receiving messages and wait for messages in the spool to be sent, at most NetConfig.Timeout. An error is returned if some messages are not sent.
+func (this *shipper) Close() error {
+
+    this.mu.Lock()
+    if this.closed {
+        this.mu.Unlock()
+        <-this.done
+        return nil
+    }
+    this.closed = true
+    started := this.started
+    this.mu.Unlock()
+
+    // Nothing could be sent if the shipper is never added to a logger.
+    if !started {
+        if this.stop != nil {
+            this.stop()
+        }
+        close(this.done)
+        if n := this.len(); n > 0 {
+            return fmt.Errorf("%d messages are not sent to the remote end.", n)
+        }
+        return nil
+    }
+
+    this.signal()
+
+    timer := time.NewTimer(this.Timeout)
+    defer timer.Stop()
+
+    select {
+        case <-this.done:
+        case <-timer.C:
+            close(this.abort)
+            <-this.done
+    }
+
+    if n := this.len(); n > 0 {
+        return fmt.Errorf("%d messages are not sent to the remote end.", n)
+    }
+    return nil
+}
+
+
+// An error of messages the remote end never accepts, e.g. a datagram too large or an HTTP status 400. The messages are dropped instead of sent again.
+type rejectedError struct {
+    n int       // Number of messages rejected, they are counted in the number of messages sent.
+    err error
+}
+
+
+func (this *rejectedError) Error() string {
+    return this.err.Error()
+}
+
+
+// ------------------------------------------------
+// connSender
+
+
+// Send messages through a connection, which is made on demand and remade after it fails. Only used in the shipper's goroutine.
+type connSender struct {
+    network string
+    addr string
+    timeout time.Duration
+    conn net.Conn
+}
+
+
+func (this *connSender) send(batch [][]byte) (n int, err error) {
+
+    if this.conn == nil {
+        this.conn, err = net.DialTimeout(this.network, this.addr, this.timeout)
+        if err != nil {
+            this.conn = nil
+            return
+        }
+    }
+
+    for _, b := range batch {
+        this.conn.SetWriteDeadline(time.Now().Add(this.timeout))
+        var written int
+        written, err = this.conn.Write(b)
+        if err != nil {
+            // A datagram too large is never sent, and the connection is still usable.
+            if written == 0 && !isStreamNetwork(this.network) && isMessageTooLong(err) {
+                n++
+                err = &rejectedError{n: 1, err: err}
+                return
+            }
+            this.close()
+            // Sending the message again makes a torn or duplicate record on a stream, so a partially sent message is dropped.
+            if written > 0 {
+                n++
+                err = fmt.Errorf("Message is partially sent and dropped: %v", err)
+            }
+            return
+        }
+        n++
+    }
+    return
+}
+
+
+func (this *connSender) close() {
+    if this.conn != nil {
+        this.conn.Close()
+        this.conn = nil
+    }
+}
+
+
+// Check if a network is stream oriented, i.e. messages need framing.
+func isStreamNetwork(network string) bool {
+    switch network {
+        case "tcp", "tcp4", "tcp6", "unix":
+            return true
+    }
+    return false
+}
+
+
+// ------------------------------------------------
+// JSONSink
+
+
+/* A sink sends messages as newline-delimited JSON over TCP or unix socket, in the format of JSONFormatter.
+
+Example:
+    sink, err := log.NewJSONSink("tcp", "logs.example.com:5170", log.NetConfig{})
+    if err != nil {
+        return err
+    }
+    logger.AddSink(sink, log.INFO)
+*/
+type JSONSink struct {
+    *shipper
+    formatter JSONFormatter
+}
+
+
+// Create a JSONSink. Network could be "tcp", "tcp4", "tcp6" or "unix".
+func NewJSONSink(network, addr string, config NetConfig) (*JSONSink, error) {
+
+    if !isStreamNetwork(network) {
+        return nil, fmt.Errorf("Network is not supported: %s", network)
+    }
+
+    setNetConfigDefault(&config)
+    if err := isNetConfigLegal(&config); err != nil {
+        return nil, err
+    }
+
+    sender := &connSender{network: network, addr: addr, timeout: config.Timeout}
+    return &JSONSink{shipper: newShipper(config, 100, 0, sender.send, sender.close)}, nil
+}
+
+
+func (this *JSONSink) WriteMessage(m Message) error {
+    return this.push(this.formatter.Format(m))
+}
@@ -0,0 +1,469 @@
+package log
+
+import "testing"
+import "bufio"
+import "bytes"
+import "context"
+import "encoding/json"
+import "errors"
+import "io/ioutil"
+import "net"
+import "net/http"
+import "net/http/httptest"
+import "regexp"
+import "strconv"
+import "strings"
+import "sync"
+import "time"
+
+
+// A logger writes nothing but to the sinks added.
+func newSinkLogger(t *testing.T, sink Sink) *Logger {
+    logger, err := New(ioutil.Discard, Config{Level: FATAL})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err = logger.AddSink(sink, DEBUG); err != nil {
+        t.Fatal(err)
+    }
+    return logger
+}
+
+
+func TestShipperSpool(t *testing.T) {
+
+    var mu sync.Mutex
+    down := true
+    var sent []string
+
+    send := func(batch [][]byte) (int, error) {
+        mu.Lock()
+        defer mu.Unlock()
+        if down {
+            return 0, errors.New("remote end is down")
+        }
+        for _, b := range batch {
+            sent = append(sent, string(b))
+        }
+        return len(batch), nil
+    }
+
+    s := newShipper(NetConfig{SpoolSize: 3, MinBackoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond, Timeout: time.Second}, 2, 0, send, nil)
+    logger, _ := New(ioutil.Discard, Config{})
+    if err := s.attach(logger); err != nil {
+        t.Fatal(err)
+    }
+    for _, b := range []string{"1", "2", "3", "4", "5"} {
+        s.push([]byte(b))
+    }
+    time.Sleep(50 * time.Millisecond)
+    if n := s.len(); n != 3 {
+        t.Errorf("Unexpected spool size: %d", n)
+    }
+
+    mu.Lock()
+    down = false
+    mu.Unlock()
+
+    if err := s.Close(); err != nil {
+        t.Error(err)
+    }
+    if strings.Join(sent, ",") != "3,4,5" {
+        t.Errorf("Unexpected sent messages: %v", sent)
+    }
+    if err := s.push([]byte("6")); err == nil {
+        t.Error("Closed shipper should not accept messages.")
+    }
+}
+
+
+func TestSyslogSink(t *testing.T) {
+
+    conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer conn.Close()
+
+    sink, err := NewSyslogSink("udp", conn.LocalAddr().String(), SyslogConfig{Facility: 16, Hostname: "host", Tag: "app"})
+    if err != nil {
+        t.Fatal(err)
+    }
+    logger := newSinkLogger(t, sink)
+    logger.Warnw("disk is full", "path", "/data")
+    logger.Close(context.Background())
+
+    buf := make([]byte, 1024)
+    conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    n, _, err := conn.ReadFrom(buf)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    re := regexp.MustCompile(`^<132>1 \d{4}-\d\d-\d\dT\d\d:\d\d:\d\d\.\d{6}(Z|[+-]\d\d:\d\d) host app \d+ - - disk is full path=/data$`)
+    if !re.Match(buf[:n]) {
+        t.Errorf("Unexpected syslog message: %q", buf[:n])
+    }
+
+    if _, err = NewSyslogSink("ip", "localhost", SyslogConfig{}); err == nil {
+        t.Error("Network ip should not be accepted.")
+    }
+}
+
+
+// A datagram too large is dropped, and not to block messages behind it.
+func TestSyslogSinkTooLong(t *testing.T) {
+
+    conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer conn.Close()
+
+    // Messages are never sent again within the test if they are taken as failed.
+    sink, err := NewSyslogSink("udp", conn.LocalAddr().String(), SyslogConfig{NetConfig: NetConfig{MinBackoff: time.Hour}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    logger := newSinkLogger(t, sink)
+    defer logger.Close(context.Background())
+    logger.Info(strings.Repeat("x", 70000))
+    logger.Info("small")
+
+    var received []string
+    buf := make([]byte, 1024)
+    conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    for len(received) < 2 {
+        n, _, err := conn.ReadFrom(buf)
+        if err != nil {
+            t.Fatalf("Messages are not received: %v", received)
+        }
+        received = append(received, string(buf[:n]))
+    }
+
+    // The report of dropping is sent too, and it's the same sink so its order is not certain.
+    s := strings.Join(received, "\n")
+    if !strings.HasSuffix(received[0], " small") && !strings.HasSuffix(received[1], " small") || !strings.Contains(s, "log: dropped 1 messages rejected by the remote end") {
+        t.Errorf("Unexpected messages: %q", received)
+    }
+}
+
+
+func TestSyslogSinkStream(t *testing.T) {
+
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer ln.Close()
+
+    received := make(chan string, 1)
+    go func() {
+        conn, err := ln.Accept()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        b, _ := ioutil.ReadAll(conn)
+        received <- string(b)
+    }()
+
+    sink5424, _ := NewSyslogSink("tcp", ln.Addr().String(), SyslogConfig{Hostname: "host", Tag: "app"})
+    sink3164, _ := NewSyslogSink("tcp", ln.Addr().String(), SyslogConfig{Format: SF_RFC3164, Hostname: "host", Tag: "app"})
+
+    m := Message{Msg: "multiple\nlines", Level: ERROR, Time: time.Now()}
+    s := string(sink5424.format(m))
+    if !regexp.MustCompile(`^(\d+) <11>1 `).MatchString(s) || !strings.HasSuffix(s, " - - multiple\nlines") {
+        t.Errorf("Unexpected RFC 5424 message: %q", s)
+    } else if length := s[:strings.Index(s, " ")]; length != strconv.Itoa(len(s) - len(length) - 1) {
+        t.Errorf("Unexpected octet counting: %q", s)
+    }
+    sink5424.Close()
+
+    logger := newSinkLogger(t, sink3164)
+    logger.Error("multiple\nlines")
+    logger.Close(context.Background())
+
+    select {
+        case s := <-received:
+            re := regexp.MustCompile(`^<11>[A-Z][a-z]{2} [ \d]\d \d\d:\d\d:\d\d host app\[\d+\]: multiple\\nlines\n$`)
+            if !re.MatchString(s) {
+                t.Errorf("Unexpected RFC 3164 message: %q", s)
+            }
+        case <-time.After(5 * time.Second):
+            t.Error("No message is received.")
+    }
+}
+
+
+// Messages written while the remote end is down are sent after it's back.
+func TestJSONSinkReconnect(t *testing.T) {
+
+    ln, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    addr := ln.Addr().String()
+    ln.Close()
+
+    sink, err := NewJSONSink("tcp", addr, NetConfig{MinBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var reported bytes.Buffer
+    var mu sync.Mutex
+    logger, err := New(ioutil.Discard, Config{Level: FATAL}, Handle{Func: func(m Message) {
+        mu.Lock()
+        reported.WriteString(m.Msg + "\n")
+        mu.Unlock()
+    }, Level: ERROR})
+    if err != nil {
+        t.Fatal(err)
+    }
+    logger.AddSink(sink, INFO)
+
+    logger.Infow("first", "i", 1)
+    logger.Info("second")
+    time.Sleep(100 * time.Millisecond)
+
+    ln, err = net.Listen("tcp", addr)
+    if err != nil {
+        t.Skip("The address could not be listened again: ", err)
+    }
+    defer ln.Close()
+
+    conn, err := ln.Accept()
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer conn.Close()
+
+    var msgs []string
+    scanner := bufio.NewScanner(conn)
+    conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    for len(msgs) < 3 && scanner.Scan() {
+        var m map[string]interface{}
+        if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+            t.Fatal(err)
+        }
+        msgs = append(msgs, m["level"].(string) + " " + m["msg"].(string))
+    }
+
+    // The error of connecting is reported to the logger and sent too.
+    if len(msgs) != 3 || msgs[0] != "INFO first" || msgs[1] != "INFO second" || !strings.HasPrefix(msgs[2], "ERROR log: dial tcp") {
+        t.Errorf("Unexpected messages: %v", msgs)
+    }
+
+    logger.Close(context.Background())
+
+    mu.Lock()
+    defer mu.Unlock()
+    if strings.Count(reported.String(), "\n") != 1 {
+        t.Errorf("Failure should be reported once: %q", reported.String())
+    }
+}
+
+
+func TestHTTPSink(t *testing.T) {
+
+    var mu sync.Mutex
+    var requests int
+    var batches [][]string
+
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        defer mu.Unlock()
+
+        requests++
+        if requests == 1 {
+            http.Error(w, "unavailable", http.StatusServiceUnavailable)
+            return
+        }
+        if r.Header.Get("Content-Type") != "application/x-ndjson" || r.Header.Get("X-Token") != "abc" {
+            t.Errorf("Unexpected headers: %v", r.Header)
+        }
+
+        b, _ := ioutil.ReadAll(r.Body)
+        var msgs []string
+        for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+            var m map[string]interface{}
+            json.Unmarshal([]byte(line), &m)
+            msgs = append(msgs, m["msg"].(string))
+        }
+        batches = append(batches, msgs)
+    }))
+    defer server.Close()
+
+    sink, err := NewHTTPSink(server.URL, HTTPConfig{
+        BatchSize:      2,
+        FlushInterval:  time.Hour,
+        Header:         http.Header{"X-Token": {"abc"}},
+        NetConfig:      NetConfig{MinBackoff: 10 * time.Millisecond},
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    logger := newSinkLogger(t, sink)
+    for _, s := range []string{"1", "2", "3", "4", "5"} {
+        logger.Info(s)
+    }
+    logger.Close(context.Background())
+
+    mu.Lock()
+    defer mu.Unlock()
+
+    // The first batch is sent again after failure, and the rest are sent when the sink is closed. The failure is reported to the logger and sent too.
+    var msgs []string
+    for _, batch := range batches {
+        if len(batch) > 2 {
+            t.Errorf("Batch is too large: %v", batch)
+        }
+        for _, msg := range batch {
+            if !strings.HasPrefix(msg, "log: Unexpected status") {
+                msgs = append(msgs, msg)
+            }
+        }
+    }
+    if requests < 4 || strings.Join(msgs, ",") != "1,2,3,4,5" {
+        t.Errorf("Unexpected batches: %v", batches)
+    }
+
+    if _, err = NewHTTPSink("ftp://example.com", HTTPConfig{}); err == nil {
+        t.Error("Scheme ftp should not be accepted.")
+    }
+}
+
+
+// A batch rejected by a client error is dropped, not sent again.
+func TestHTTPSinkRejected(t *testing.T) {
+
+    var mu sync.Mutex
+    var bodies []string
+
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        mu.Lock()
+        defer mu.Unlock()
+
+        b, _ := ioutil.ReadAll(r.Body)
+        bodies = append(bodies, string(b))
+        if strings.Contains(string(b), `"msg":"bad"`) {
+            http.Error(w, "bad request", http.StatusBadRequest)
+        }
+    }))
+    defer server.Close()
+
+    sink, err := NewHTTPSink(server.URL, HTTPConfig{BatchSize: 1, NetConfig: NetConfig{MinBackoff: time.Hour}})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    logger := newSinkLogger(t, sink)
+    defer logger.Close(context.Background())
+    logger.Info("bad")
+    logger.Info("good")
+
+    // Wait for the two messages and the report of dropping.
+    var s string
+    for i := 0; i < 100; i++ {
+        mu.Lock()
+        n := len(bodies)
+        s = strings.Join(bodies, "")
+        mu.Unlock()
+        if n >= 3 {
+            break
+        }
+        time.Sleep(50 * time.Millisecond)
+    }
+
+    mu.Lock()
+    defer mu.Unlock()
+    if strings.Count(s, `"msg":"bad"`) != 1 || strings.Count(s, `"msg":"good"`) != 1 || !strings.Contains(s, "dropped 1 messages rejected by the remote end") {
+        t.Errorf("Unexpected requests: %q", bodies)
+    }
+}
+
+
+// A shipper never added to a logger starts no goroutine, and could be closed.
+func TestShipperNotAttached(t *testing.T) {
+
+    send := func(batch [][]byte) (int, error) {
+        t.Error("Nothing should be sent.")
+        return len(batch), nil
+    }
+
+    s := newShipper(NetConfig{SpoolSize: 3, Timeout: time.Second}, 2, 0, send, nil)
+    s.push([]byte("1"))
+
+    closed := make(chan error, 1)
+    go func() {
+        closed <- s.Close()
+    }()
+
+    select {
+        case err := <-closed:
+            if err == nil {
+                t.Error("Messages not sent should be reported.")
+            }
+        case <-time.After(time.Second):
+            t.Fatal("Close() does not return.")
+    }
+
+    logger, _ := New(ioutil.Discard, Config{})
+    if s.attach(logger) == nil {
+        t.Error("Closed shipper should not be added to a logger.")
+    }
+}
+
+
+// A connection which accepts limit bytes, then fails.
+type limitConn struct {
+    net.Conn
+    buf bytes.Buffer
+    limit int
+}
+
+
+func (this *limitConn) Write(b []byte) (int, error) {
+    if this.buf.Len() + len(b) <= this.limit {
+        return this.buf.Write(b)
+    }
+    n := this.limit - this.buf.Len()
+    this.buf.Write(b[:n])
+    return n, errors.New("connection reset")
+}
+
+
+func (this *limitConn) SetWriteDeadline(t time.Time) error {
+    return nil
+}
+
+
+func (this *limitConn) Close() error {
+    return nil
+}
+
+
+// A partially sent message is not sent again.
+func TestConnSenderPartial(t *testing.T) {
+
+    conn := &limitConn{limit: 8}
+    sender := &connSender{network: "tcp", timeout: time.Second, conn: conn}
+
+    n, err := sender.send([][]byte{[]byte("first\n"), []byte("second\n"), []byte("third\n")})
+    if n != 2 || err == nil || !strings.Contains(err.Error(), "partially sent") {
+        t.Errorf("Unexpected result: %d, %v", n, err)
+    }
+    if conn.buf.String() != "first\nse" || sender.conn != nil {
+        t.Errorf("Unexpected state: %q, %v", conn.buf.String(), sender.conn)
+    }
+
+    // Nothing of the failed message is sent, so it's kept to be sent again.
+    conn = &limitConn{limit: 6}
+    sender.conn = conn
+    n, err = sender.send([][]byte{[]byte("first\n"), []byte("second\n")})
+    if n != 1 || err == nil || strings.Contains(err.Error(), "partially sent") {
+        t.Errorf("Unexpected result: %d, %v", n, err)
+    }
+}
@@ -0,0 +1,131 @@
+package log
+
+import "testing"
+import "bytes"
+import "strings"
+import "sync"
+import "time"
+import "unsafe"
+
+
+// A writer blocks in Write until it is released.
+type blockWriter struct {
+    mu sync.Mutex
+    buf bytes.Buffer
+    once sync.Once
+    entered chan struct{}   // Closed when Write is called first time.
+    release chan struct{}
+}
+
+func newBlockWriter() *blockWriter {
+    return &blockWriter{entered: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (this *blockWriter) Write(b []byte) (int, error) {
+    this.once.Do(func() {
+        close(this.entered)
+    })
+    <-this.release
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    return this.buf.Write(b)
+}
+
+func (this *blockWriter) String() string {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+    return this.buf.String()
+}
+
+
+// Send messages to a logger whose writer is blocked, then release the writer.
+func testOverflow(t *testing.T, config Config, levels ...LevelType) *blockWriter {
+    w := newBlockWriter()
+
+    config.Layout      = LY_MSGONLY
+    config.LayoutStyle = "{msg}"
+    config.QueueSize   = 2
+
+    logger, err := New(w, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // The first message is taken by the log writing goroutine, which blocks in Write.
+    logger.Print(levels[0], "msg0")
+    <-w.entered
+
+    for i, level := range levels[1:] {
+        logger.Printf(level, "msg%d", i+1)
+    }
+
+    close(w.release)
+    logger.Wait()
+    return w
+}
+
+
+func TestOverflowDropNewest(t *testing.T) {
+    s := testOverflow(t, Config{Overflow: OF_DROP_NEWEST}, INFO, INFO, INFO, INFO, INFO).String()
+    if s != "msg0\nmsg1\nmsg2\n" {
+        t.Errorf("Unexpected output: %q", s)
+    }
+}
+
+
+func TestOverflowDropOldest(t *testing.T) {
+    s := testOverflow(t, Config{Overflow: OF_DROP_OLDEST}, INFO, INFO, INFO, INFO, INFO).String()
+    if s != "msg0\nmsg3\nmsg4\n" {
+        t.Errorf("Unexpected output: %q", s)
+    }
+}
+
+
+func TestOverflowDropBelow(t *testing.T) {
+    s := testOverflow(t, Config{Overflow: OF_DROP_BELOW, OverflowLevel: WARN}, INFO, INFO, INFO, INFO).String()
+    if s != "msg0\nmsg1\nmsg2\n" {
+        t.Errorf("Unexpected output: %q", s)
+    }
+}
+
+
+func TestReportDropped(t *testing.T) {
+    interval := dropReportInterval
+    dropReportInterval = 20 * time.Millisecond
+    defer func() {
+        dropReportInterval = interval
+    }()
+
+    w := testOverflow(t, Config{Overflow: OF_DROP_NEWEST}, INFO, INFO, INFO, INFO, INFO)
+
+    // The report is written by a ticker, wait for it.
+    for i := 0; i < 50; i++ {
+        if strings.HasSuffix(w.String(), "log: dropped 2 messages\n") {
+            return
+        }
+        time.Sleep(20 * time.Millisecond)
+    }
+    t.Errorf("Unexpected output: %q", w.String())
+}
+
+
+// 64-bit atomic operations need 64-bit aligned fields on 32-bit platforms.
+func TestCountersAligned(t *testing.T) {
+    if offset := unsafe.Offsetof(state{}.dropped); offset % 8 != 0 {
+        t.Errorf("state.dropped is not 64-bit aligned, offset: %d", offset)
+    }
+
+    offsets := map[string]uintptr{
+        "loggerCounters.accepted":  unsafe.Offsetof(loggerCounters{}.accepted),
+        "loggerCounters.dropped":   unsafe.Offsetof(loggerCounters{}.dropped),
+        "sinkCounters.messages":    unsafe.Offsetof(sinkCounters{}.messages),
+        "sinkCounters.bytes":       unsafe.Offsetof(sinkCounters{}.bytes),
+        "sinkCounters.rotations":   unsafe.Offsetof(sinkCounters{}.rotations),
+        "sinkCounters.errors":      unsafe.Offsetof(sinkCounters{}.errors),
+    }
+    for name, offset := range offsets {
+        if offset % 8 != 0 {
+            t.Errorf("%s is not 64-bit aligned, offset: %d", name, offset)
+        }
+    }
+}
@@ -0,0 +1,485 @@
+package log
+
+import "bufio"
+import "compress/gzip"
+import "errors"
+import "io"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "regexp"
+import "sort"
+import "strconv"
+import "strings"
+import "sync"
+import "time"
+
+
+// ------------------------------------------------
+// Reader
+
+
+// How often a following Reader checks for new content and rotation.
+var followInterval = 200 * time.Millisecond
+
+
+// Marks in layout style.
+var markRegexp = regexp.MustCompile(`\{(time|level|msg|fields|caller|name)\}`)
+
+
+// Regexps of keys and values in Fields.String(), quoted or not. See quoteIfNeeded().
+const quotedExp     = `"(?:[^"\\]|\\.)*"`
+const fieldKeyExp   = `(?:` + quotedExp + `|[^\s="\\]+)`
+const fieldValueExp = `(?:` + quotedExp + `|[^\s="\\]*)`
+const fieldExp      = fieldKeyExp + `=` + fieldValueExp
+
+var fieldRegexp = regexp.MustCompile(`(` + fieldKeyExp + `)=(` + fieldValueExp + `)`)
+
+
+/* Reader parses log files written by a TemplateFormatter back to messages.
+
+The time, level, name, msg, caller and fields of messages are parsed by the layout style and time format of the config. A line which could not be parsed is taken as a continuation of the previous message, e.g. a message with newlines or a stack trace, and appended to its Msg. Fields are parsed from the end of a line, so a message ending with something like "key=value" could not be told from fields. Values of parsed fields are strings.
+
+Example:
+    reader, err := log.NewReader("/var/log/app.log", config, false)
+    if err != nil {
+        return err
+    }
+    defer reader.Close()
+
+    for {
+        m, err := reader.Next()
+        if err == io.EOF {
+            break
+        }
+        if err != nil {
+            return err
+        }
+        fmt.Println(m.Time, m.Level, m.Msg)
+    }
+*/
+type Reader struct {
+    config Config
+    filename string
+    follow bool
+    re *regexp.Regexp       // Regexp of a line made by the layout style.
+    names []string          // Files to be read after the current one.
+
+    mu sync.Mutex           // Held by Next(), so Close() waits for it.
+    file *os.File
+    r *bufio.Reader
+    gz *gzip.Reader
+    partial string          // A line without trailing newline, waiting for the rest in follow mode.
+    pending *Message        // A parsed message, waiting for its continuation lines.
+    draining bool           // The followed file has been rotated, read what's left in it before switching to the new file.
+    closed chan struct{}
+    closeOnce sync.Once
+}
+
+
+/* Create a Reader of a log file written by config. Only a TemplateFormatter (the default formatter) is supported.
+
+If follow is false, files rotated from filename (matched by config.RotatePattern, including compressed ones) are read first, oldest first, then filename. Next() returns io.EOF at the end.
+
+If follow is true, the reader works like "tail -f": it starts at the end of filename and waits for new messages, and switches to the new file when filename is rotated or reopened. Next() blocks until a message is written or the reader is closed.
+*/
+func NewReader(filename string, config Config, follow bool) (reader *Reader, err error) {
+
+    setConfigDefault(&config)
+
+    if config.Formatter != nil {
+        formatter, ok := config.Formatter.(*TemplateFormatter)
+        if !ok {
+            err = errors.New("Only TemplateFormatter could be parsed by Reader.")
+            return
+        }
+        if formatter.Style != "" {
+            config.LayoutStyle = formatter.Style
+        }
+        if formatter.TimeFormat != "" {
+            config.TimeFormat = formatter.TimeFormat
+        }
+    }
+
+    err = isLayoutLegal(config.Layout, config.LayoutStyle)
+    if err != nil {
+        return
+    }
+
+    reader = &Reader{
+        config:     config,
+        filename:   filename,
+        follow:     follow,
+        re:         layoutRegexp(config.LayoutStyle),
+        closed:     make(chan struct{}),
+    }
+
+    if follow {
+        // The file may not exist yet, it's opened when it appears.
+        if reader.open(filename) == nil {
+            _, err = reader.file.Seek(0, io.SeekEnd)
+            if err != nil {
+                reader.file.Close()
+                reader = nil
+            }
+        }
+        return
+    }
+
+    if config.rotatable() {
+        reader.names, err = rotatedFiles(filename, config)
+        if err != nil {
+            reader = nil
+            return
+        }
+    }
+    reader.names = append(reader.names, filename)
+
+    err = reader.openNext()
+    if err != nil {
+        reader = nil
+    }
+    return
+}
+
+
+// Make a regexp which matches a line formatted by a layout style, with a submatch for each mark.
+func layoutRegexp(style string) *regexp.Regexp {
+
+    var b strings.Builder
+    b.WriteString("^")
+
+    last := 0
+    for _, loc := range markRegexp.FindAllStringSubmatchIndex(style, -1) {
+        literal := style[last:loc[0]]
+        mark := style[loc[2]:loc[3]]
+        last = loc[1]
+
+        var exp string
+        switch mark {
+            case "time":
+                exp = `(?P<time>.+?)`
+            case "level":
+                exp = `(?P<level>DEBUG|NOTICE|INFO|WARN|ERROR|FATAL)`
+            case "msg":
+                exp = `(?P<msg>.*?)`
+            case "fields":
+                exp = `(?P<fields>` + fieldExp + `(?: ` + fieldExp + `)*)`
+            case "caller":
+                exp = `(?P<caller>\S+:\d+)`
+            case "name":
+                exp = `(?P<name>\S+)`
+        }
+
+        // An empty {fields}, {caller} or {name} is removed with the space before it.
+        if (mark == "fields" || mark == "caller" || mark == "name") && strings.HasSuffix(literal, " ") {
+            b.WriteString(regexp.QuoteMeta(literal[:len(literal) - 1]))
+            b.WriteString("(?: " + exp + ")?")
+        } else {
+            b.WriteString(regexp.QuoteMeta(literal))
+            b.WriteString(exp)
+        }
+    }
+
+    b.WriteString(regexp.QuoteMeta(style[last:]))
+    b.WriteString("$")
+
+    return regexp.MustCompile(b.String())
+}
+
+
+// Parse a line to a message. If the line does not match the layout style, ok is false.
+func (this *Reader) parseLine(line string) (m Message, ok bool) {
+
+    match := this.re.FindStringSubmatch(line)
+    if match == nil {
+        return
+    }
+
+    location := time.Local
+    if this.config.Utc {
+        location = time.UTC
+    }
+
+    for i, name := range this.re.SubexpNames() {
+        value := match[i]
+        switch name {
+            case "time":
+                t, err := time.ParseInLocation(this.config.TimeFormat, value, location)
+                if err != nil {
+                    return
+                }
+                m.Time = t
+            case "level":
+                m.Level, _ = String2Level(value)
+            case "msg":
+                m.Msg = value
+            case "fields":
+                m.Fields = parseFields(value)
+            case "name":
+                m.Name = value
+            case "caller":
+                idx := strings.LastIndex(value, ":")
+                if idx > 0 {
+                    m.Caller.File = value[:idx]
+                    m.Caller.Line, _ = strconv.Atoi(value[idx+1:])
+                }
+        }
+    }
+
+    ok = true
+    return
+}
+
+
+
+// Parse a string made by Fields.String().
+func parseFields(s string) Fields {
+    var fields Fields
+    for _, match := range fieldRegexp.FindAllStringSubmatch(s, -1) {
+        fields = append(fields, Field{Key: unquoteIfNeeded(match[1]), Value: unquoteIfNeeded(match[2])})
+    }
+    return fields
+}
+
+
+// Unquote a string quoted by quoteIfNeeded().
+func unquoteIfNeeded(s string) string {
+    if strings.HasPrefix(s, `"`) {
+        if unquoted, err := strconv.Unquote(s); err == nil {
+            return unquoted
+        }
+    }
+    return s
+}
+
+
+// Get files rotated from filename by config, the oldest first.
+func rotatedFiles(filename string, config Config) (names []string, err error) {
+
+    dir := filepath.Dir(filename)
+
+    entries, err := ioutil.ReadDir(dir)
+    if err != nil {
+        return
+    }
+
+    re := (&WriterSink{Config: config}).rotatedRegexp(filename)
+
+    var files []os.FileInfo
+    for _, entry := range entries {
+        if entry.Mode().IsRegular() && re.MatchString(entry.Name()) {
+            files = append(files, entry)
+        }
+    }
+
+    sort.Slice(files, func(i, j int) bool {
+        if files[i].ModTime().Equal(files[j].ModTime()) {
+            return naturalLess(files[i].Name(), files[j].Name())
+        }
+        return files[i].ModTime().Before(files[j].ModTime())
+    })
+
+    for _, file := range files {
+        names = append(names, filepath.Join(dir, file.Name()))
+    }
+    return
+}
+
+
+// Open a file to read, a compressed file is decompressed.
+func (this *Reader) open(name string) (err error) {
+
+    file, err := os.Open(name)
+    if err != nil {
+        return
+    }
+
+    this.closeFile()
+    this.file = file
+
+    if strings.HasSuffix(name, ".gz") {
+        this.gz, err = gzip.NewReader(file)
+        if err != nil {
+            return
+        }
+        this.r = bufio.NewReader(this.gz)
+    } else {
+        this.r = bufio.NewReader(file)
+    }
+    return
+}
+
+
+// Open the next file in names. If no file is left, io.EOF is returned.
+func (this *Reader) openNext() error {
+    for len(this.names) > 0 {
+        name := this.names[0]
+        this.names = this.names[1:]
+
+        err := this.open(name)
+        // A rotated file may be removed by cleanup, or compressed after it's listed.
+        if os.IsNotExist(err) {
+            if len(this.names) > 0 {
+                continue
+            }
+        }
+        return err
+    }
+    return io.EOF
+}
+
+
+func (this *Reader) closeFile() {
+    if this.gz != nil {
+        this.gz.Close()
+        this.gz = nil
+    }
+    if this.file != nil {
+        this.file.Close()
+        this.file = nil
+    }
+    this.r = nil
+}
+
+
+// Read a line without the trailing newline. In follow mode, a line without trailing newline is kept until the rest is written.
+func (this *Reader) readLine() (string, error) {
+
+    if this.r == nil {
+        return "", io.EOF
+    }
+
+    line, err := this.r.ReadString('\n')
+    if err == nil {
+        line = this.partial + line
+        this.partial = ""
+        return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+    }
+    if err != io.EOF {
+        return "", err
+    }
+
+    // The last line of a file is complete if the file is not followed, or it has been rotated.
+    this.partial += line
+    if (!this.follow || this.draining) && this.partial != "" {
+        line = this.partial
+        this.partial = ""
+        return line, nil
+    }
+    return "", io.EOF
+}
+
+
+// Wait for new content of the followed file. If the file is rotated, what's left in the old file is read first, then the new file is opened. If the reader is closed, io.EOF is returned.
+func (this *Reader) wait() error {
+
+    if this.draining {
+        this.draining = false
+        if this.open(this.filename) == nil {
+            return nil
+        }
+    }
+
+    if info, err := os.Stat(this.filename); err == nil {
+        if this.file == nil {
+            if this.open(this.filename) == nil {
+                return nil
+            }
+        } else if current, err := this.file.Stat(); err == nil {
+            if !os.SameFile(info, current) {
+                // Read the old file once more before switching, in case something is written after the last read.
+                this.draining = true
+                return nil
+            }
+            if offset, err := this.file.Seek(0, io.SeekCurrent); err == nil && info.Size() < offset {
+                // The file is truncated.
+                this.file.Seek(0, io.SeekStart)
+                this.r.Reset(this.file)
+                this.partial = ""
+                return nil
+            }
+        }
+    }
+
+    select {
+        case <-this.closed:
+            return io.EOF
+        case <-time.After(followInterval):
+            return nil
+    }
+}
+
+
+// Get the next message. At the end of files, io.EOF is returned, or wait for new messages in follow mode. After the reader is closed, io.EOF is returned.
+func (this *Reader) Next() (m Message, err error) {
+
+    this.mu.Lock()
+    defer this.mu.Unlock()
+
+    for {
+        select {
+            case <-this.closed:
+                err = io.EOF
+                return
+            default:
+        }
+
+        var line string
+        line, err = this.readLine()
+
+        if err == nil {
+            parsed, ok := this.parseLine(line)
+            if !ok {
+                if this.pending != nil {
+                    this.pending.Msg += "\n" + line
+                    continue
+                }
+                // A line without the previous message.
+                parsed = Message{Msg: line}
+            }
+
+            previous := this.pending
+            this.pending = &parsed
+            if previous != nil {
+                return *previous, nil
+            }
+            continue
+        }
+
+        if err != io.EOF {
+            return
+        }
+
+        // A message never spans files, and a whole message is written at once, so the message is complete at the end of a file.
+        if this.pending != nil && this.partial == "" {
+            m = *this.pending
+            this.pending = nil
+            err = nil
+            return
+        }
+
+        if this.follow {
+            err = this.wait()
+        } else {
+            err = this.openNext()
+        }
+        if err != nil {
+            return
+        }
+    }
+}
+
+
+// Close the reader. A blocking Next() in follow mode returns io.EOF.
+func (this *Reader) Close() error {
+    this.closeOnce.Do(func() {
+        close(this.closed)
+    })
+
+    this.mu.Lock()
+    defer this.mu.Unlock()
+
+    this.closeFile()
+    return nil
+}
@@ -0,0 +1,225 @@
+package log
+
+import "testing"
+import "context"
+import "io"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "strings"
+import "time"
+
+
+func TestParseLine(t *testing.T) {
+
+    reader := &Reader{config: Config{TimeFormat: TF_DEFAULT}, re: layoutRegexp("[{time}] {level} {caller}: {msg} {fields}")}
+
+    tests := []struct {
+        line    string
+        ok      bool
+        msg     string
+        caller  string
+        fields  string
+    }{
+        {"[2016-01-02 15:04:05.000000] INFO log/a.go:12: user login user=bob \"a b\"=\"x y\"", true, "user login", "log/a.go:12", `user=bob "a b"="x y"`},
+        {"[2016-01-02 15:04:05.000000] WARN: no caller", true, "no caller", "", ""},
+        {"[2016-01-02 15:04:05.000000] ERROR: a = b k=", true, "a = b", "", `k=""`},
+        {"[2016-01-02] INFO: wrong time", false, "", "", ""},
+        {"\tat main.go:12", false, "", "", ""},
+    }
+
+    for _, test := range tests {
+        m, ok := reader.parseLine(test.line)
+        if ok != test.ok {
+            t.Errorf("%q: expect %v, got %v", test.line, test.ok, ok)
+            continue
+        }
+        if !ok {
+            continue
+        }
+        if m.Msg != test.msg || m.Caller.String() != test.caller || m.Fields.String() != test.fields {
+            t.Errorf("%q: unexpected message %+v", test.line, m)
+        }
+        if m.Time.Format(TF_DEFAULT) != "2016-01-02 15:04:05.000000" {
+            t.Errorf("%q: unexpected time %v", test.line, m.Time)
+        }
+    }
+}
+
+
+// Read messages of a reader until io.EOF.
+func readAll(t *testing.T, reader *Reader) (messages []Message) {
+    for {
+        m, err := reader.Next()
+        if err == io.EOF {
+            return
+        }
+        if err != nil {
+            t.Fatal(err)
+        }
+        messages = append(messages, m)
+    }
+}
+
+
+func TestReader(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+
+    var config Config
+    config.LayoutStyle = LS_FIELDS
+    config.Level       = DEBUG
+    config.MaxSize     = 100
+    config.Compress    = true
+    config.Utc         = true
+
+    logger, err := New(file, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for i := 0; i < 10; i++ {
+        logger.Infow("message", "i", i)
+        time.Sleep(10 * time.Millisecond)
+    }
+    logger.Error("multiple\nlines")
+    logger.Close(context.Background())
+
+    reader, err := NewReader(file.Name(), config, false)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer reader.Close()
+
+    messages := readAll(t, reader)
+    if len(messages) != 11 {
+        t.Fatalf("Unexpected number of messages: %d", len(messages))
+    }
+    for i, m := range messages[:10] {
+        if m.Level != INFO || m.Msg != "message" || m.Fields.String() != "i=" + string(rune('0' + i)) || m.Time.Location() != time.UTC {
+            t.Errorf("Unexpected message %d: %+v", i, m)
+        }
+    }
+    if m := messages[10]; m.Level != ERROR || m.Msg != "multiple\nlines" {
+        t.Errorf("Unexpected message: %+v", m)
+    }
+
+    // At least one rotated file is compressed.
+    names, _ := filepath.Glob(filepath.Join(dir, "*.gz"))
+    if len(names) == 0 {
+        t.Error("No compressed file is read.")
+    }
+
+    if _, err = NewReader(file.Name(), Config{Formatter: &JSONFormatter{}}, false); err == nil {
+        t.Error("JSONFormatter should not be accepted.")
+    }
+}
+
+
+func TestReaderFollow(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+
+    interval := followInterval
+    followInterval = 10 * time.Millisecond
+    defer func() {
+        followInterval = interval
+    }()
+
+    config := Config{LayoutStyle: LS_DEFAULT, MaxSize: 60}
+
+    logger, err := New(file, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer logger.Close(context.Background())
+
+    logger.Info("before following")
+    logger.Wait()
+
+    reader, err := NewReader(file.Name(), config, true)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    received := make(chan string, 10)
+    go func() {
+        for {
+            m, err := reader.Next()
+            if err != nil {
+                close(received)
+                return
+            }
+            received <- m.Msg
+        }
+    }()
+
+    // Every message makes the file rotated by size. Like "tail -F", files rotated before the reader notices are not read, so messages are written slowly.
+    var expected []string
+    for i := 0; i < 5; i++ {
+        msg := "after following " + string(rune('0' + i))
+        expected = append(expected, msg)
+        logger.Info(msg)
+        logger.Wait()
+        time.Sleep(5 * followInterval)
+    }
+
+    var result []string
+    for range expected {
+        select {
+            case msg := <-received:
+                result = append(result, msg)
+            case <-time.After(5 * time.Second):
+                t.Fatalf("Messages are not received: %v", result)
+        }
+    }
+    if strings.Join(result, ",") != strings.Join(expected, ",") {
+        t.Errorf("Unexpected messages: %v", result)
+    }
+
+    reader.Close()
+    if _, ok := <-received; ok {
+        t.Error("Next() should return an error after closed.")
+    }
+}
+
+
+// Rotated files with the same modification time are sorted by {seq} as numbers.
+func TestRotatedFilesOrder(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+    file.Close()
+
+    config := Config{MaxSize: 100, RotatePattern: RP_SEQ}
+    mtime := time.Now().Add(-time.Hour)
+
+    var expected []string
+    for _, seq := range []string{"1", "2", "9", "10", "11"} {
+        name := filepath.Join(dir, "2016-01-02_" + seq + "_test.log")
+        if err := ioutil.WriteFile(name, nil, 0644); err != nil {
+            t.Fatal(err)
+        }
+        os.Chtimes(name, mtime, mtime)
+        expected = append(expected, name)
+    }
+
+    names, err := rotatedFiles(file.Name(), config)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if strings.Join(names, "\n") != strings.Join(expected, "\n") {
+        t.Errorf("Unexpected order: %v", names)
+    }
+
+    for _, test := range []struct{ a, b string; less bool }{
+        {"a_9.log", "a_10.log", true},
+        {"a_10.log", "a_9.log", false},
+        {"a_09.log", "a_10.log", true},
+        {"a.log", "a.log.gz", true},
+        {"2016-01-02_3", "2016-01-03_1", true},
+    } {
+        if naturalLess(test.a, test.b) != test.less {
+            t.Errorf("naturalLess(%q, %q) should be %v", test.a, test.b, test.less)
+        }
+    }
+}
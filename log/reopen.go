@@ -0,0 +1,162 @@
+package log
+
+import "errors"
+import "io"
+import "os"
+import "os/signal"
+
+
+// Get permission bits of an opened file. If failed, return the default mode used by OpenFile.
+func fileMode(file *os.File) os.FileMode {
+    info, err := file.Stat()
+    if err != nil {
+        return 0640
+    }
+    return info.Mode().Perm()
+}
+
+
+/* Close log files and open them again with the same path and mode, by OpenFile. Sinks whose writer is not a file, or is stdout or stderr, are skipped. If no file could be reopened, the error of the primary sink is returned.
+
+It's useful when the log file is renamed or removed by an external program like logrotate. Messages sent before and after calling Reopen are all written, it's safe to call Reopen while other goroutines are logging.
+*/
+func (this *Logger) Reopen() error {
+    result := make(chan error, 1)
+    err := this.run(func() {
+        sinks := []*WriterSink{this.primary}
+        for _, entry := range this.state.sinks {
+            if sink, ok := entry.sink.(*WriterSink); ok {
+                sinks = append(sinks, sink)
+            }
+        }
+
+        var err, skipErr error
+        reopened := false
+
+        for _, sink := range sinks {
+            if e := sink.reopenable(); e != nil {
+                if skipErr == nil {
+                    skipErr = e
+                }
+                continue
+            }
+            reopened = true
+            if e := sink.reopen(); e != nil && err == nil {
+                err = e
+            }
+        }
+
+        if !reopened {
+            err = skipErr
+        }
+        result <- err
+    })
+    if err != nil {
+        return err
+    }
+    return <-result
+}
+
+
+// Check if the writer is a file which could be reopened.
+func (this *WriterSink) reopenable() error {
+    return reopenableWriter(this.w)
+}
+
+
+// Check if a writer is a file which could be reopened.
+func reopenableWriter(w io.Writer) error {
+    file, ok := w.(*os.File)
+    if !ok {
+        return errors.New("Writer is not file, could not be reopened.")
+    }
+    if file == os.Stdout || file == os.Stderr {
+        return errors.New("Stdout or stderr could not be reopened.")
+    }
+    return nil
+}
+
+
+// Reopen the log file, must be called in the log writing goroutine.
+func (this *WriterSink) reopen() error {
+    this.closeFile()
+    return this.openFile()
+}
+
+
+// Close the log file before it's rotated or reopened. Its permission bits are kept to open it again.
+func (this *WriterSink) closeFile() {
+
+    // The file has been closed if opening it again failed.
+    if this.openFailed {
+        return
+    }
+
+    file := this.w.(*os.File)
+    this.mode = fileMode(file)
+
+    if err := file.Close(); err != nil {
+        reportTo(this.logger, err)
+    }
+}
+
+
+// Open the log file by its path after it's closed by closeFile(). If failed, the closed file is kept, writing to it returns errors rather than panics, and opening is retried before the next write.
+func (this *WriterSink) openFile() error {
+
+    this.size = 0
+
+    file, err := OpenFile(this.filename, this.mode)
+    if err != nil {
+        this.openFailed = true
+        return err
+    }
+    this.w = file
+    this.openFailed = false
+
+    if info, err := file.Stat(); err == nil {
+        this.size = info.Size()
+    }
+
+    return nil
+}
+
+
+/* Reopen the log file when receiving the signals, SIGHUP is used if no signal is given. On js, where there is no SIGHUP, it does nothing if no signal is given. Errors of reopening are reported by the logger. Call the returned function to stop it.
+
+Example:
+    // Work with logrotate's "create" policy, which sends SIGHUP after renaming the log file.
+    stop := logger.ReopenOnSignal()
+    defer stop()
+*/
+func (this *Logger) ReopenOnSignal(sig ...os.Signal) (stop func()) {
+
+    if len(sig) == 0 {
+        if reopenSignal == nil {
+            return func() {}
+        }
+        sig = []os.Signal{reopenSignal}
+    }
+
+    c := make(chan os.Signal, 1)
+    done := make(chan struct{})
+    signal.Notify(c, sig...)
+
+    go func() {
+        for {
+            select {
+                case <-c:
+                    if err := this.Reopen(); err != nil {
+                        this.report(err)
+                    }
+                case <-done:
+                    return
+            }
+        }
+    }()
+
+    return func() {
+        signal.Stop(c)
+        close(done)
+    }
+}
@@ -0,0 +1,10 @@
+//go:build !js
+
+package log
+
+import "os"
+import "syscall"
+
+
+// The signal used by ReopenOnSignal() if no signal is given.
+var reopenSignal os.Signal = syscall.SIGHUP
@@ -0,0 +1,7 @@
+package log
+
+import "os"
+
+
+// There is no SIGHUP on js, so ReopenOnSignal() does nothing if no signal is given.
+var reopenSignal os.Signal
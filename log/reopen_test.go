@@ -0,0 +1,62 @@
+package log
+
+import "testing"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+
+
+func TestReopen(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+
+    var config Config
+    config.Layout      = LY_MSGONLY
+    config.LayoutStyle = "{msg}"
+
+    logger, err := New(file, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    logger.Info("before")
+    logger.Wait()
+
+    // Rename the log file like logrotate does.
+    filename := filepath.Join(dir, "test.log")
+    renamed := filepath.Join(dir, "test.log.1")
+    err = os.Rename(filename, renamed)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    err = logger.Reopen()
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    logger.Info("after")
+    logger.Wait()
+
+    for name, content := range map[string]string{renamed: "before\n", filename: "after\n"} {
+        b, err := ioutil.ReadFile(name)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if string(b) != content {
+            t.Errorf("Unexpected content of %s: %q", name, b)
+        }
+    }
+}
+
+
+func TestReopenStdout(t *testing.T) {
+    logger, err := New(os.Stdout, Config{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    err = logger.Reopen()
+    if err == nil || err.Error() != "Stdout or stderr could not be reopened." {
+        t.Errorf("Unexpected error: %v", err)
+    }
+}
@@ -0,0 +1,218 @@
+package log
+
+import "bytes"
+import "errors"
+import "net/http"
+import "strconv"
+import "strings"
+import "sync"
+import "time"
+
+
+// ------------------------------------------------
+// RingSink
+
+
+/* A sink keeps the last N messages in memory, e.g. for viewing recent logs on an admin page. The level of the sink is independent of other sinks.
+
+Example:
+    ring := log.NewRingSink(1000)
+    logger.AddSink(ring, log.DEBUG)
+    http.Handle("/debug/logs", ring.Handler())
+*/
+type RingSink struct {
+    mu sync.Mutex
+    buf []Message
+    next int        // Index of the next message to write.
+    full bool       // Whether buf is full, i.e. the oldest message is at next.
+}
+
+
+// Create a RingSink which keeps the last size messages.
+func NewRingSink(size int) (*RingSink, error) {
+    if size <= 0 {
+        return nil, errors.New("Size of ring sink must be greater than 0.")
+    }
+    return &RingSink{buf: make([]Message, size)}, nil
+}
+
+
+func (this *RingSink) WriteMessage(m Message) error {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+
+    this.buf[this.next] = m
+    this.next++
+    if this.next == len(this.buf) {
+        this.next = 0
+        this.full = true
+    }
+    return nil
+}
+
+
+// Get messages in the sink, the oldest first.
+func (this *RingSink) Messages() []Message {
+    this.mu.Lock()
+    defer this.mu.Unlock()
+
+    if !this.full {
+        return append([]Message(nil), this.buf[:this.next]...)
+    }
+
+    messages := make([]Message, 0, len(this.buf))
+    messages = append(messages, this.buf[this.next:]...)
+    return append(messages, this.buf[:this.next]...)
+}
+
+
+// Conditions to filter messages, see RingSink.Handler().
+type messageFilter struct {
+    level LevelType
+    since time.Time
+    until time.Time
+    q string
+    limit int
+}
+
+
+// Parse a time in RFC 3339, or a duration before now like "10m".
+func parseFilterTime(s string, now time.Time) (time.Time, error) {
+    if d, err := time.ParseDuration(s); err == nil {
+        return now.Add(-d), nil
+    }
+    return time.Parse(time.RFC3339, s)
+}
+
+
+// Parse filter conditions from URL query.
+func parseMessageFilter(r *http.Request) (filter messageFilter, err error) {
+
+    query := r.URL.Query()
+    now := time.Now()
+
+    if s := query.Get("level"); s != "" {
+        var ok bool
+        filter.level, ok = String2Level(s)
+        if !ok {
+            err = errors.New("Log level is not legal: " + s)
+            return
+        }
+    }
+
+    if s := query.Get("since"); s != "" {
+        filter.since, err = parseFilterTime(s, now)
+        if err != nil {
+            err = errors.New("Time is not legal: " + s)
+            return
+        }
+    }
+
+    if s := query.Get("until"); s != "" {
+        filter.until, err = parseFilterTime(s, now)
+        if err != nil {
+            err = errors.New("Time is not legal: " + s)
+            return
+        }
+    }
+
+    if s := query.Get("limit"); s != "" {
+        filter.limit, err = strconv.Atoi(s)
+        if err != nil || filter.limit < 0 {
+            err = errors.New("Limit is not legal: " + s)
+            return
+        }
+    }
+
+    filter.q = query.Get("q")
+    return
+}
+
+
+func (this *messageFilter) match(m *Message) bool {
+    if m.Level < this.level {
+        return false
+    }
+    if !this.since.IsZero() && m.Time.Before(this.since) {
+        return false
+    }
+    if !this.until.IsZero() && m.Time.After(this.until) {
+        return false
+    }
+    if this.q != "" && !strings.Contains(m.Msg, this.q) && !strings.Contains(m.Fields.String(), this.q) {
+        return false
+    }
+    return true
+}
+
+
+/* Create an http.Handler to view messages in the sink, the oldest first.
+
+Query parameters:
+    level       the lowest level of messages, e.g. "warn".
+    since       messages at or after the time, in RFC 3339 or a duration before now like "10m".
+    until       messages at or before the time, in the same format as since.
+    q           messages whose msg or fields contain the string.
+    limit       the maximum number of messages, the latest ones are kept.
+    format      "json" (default) for an array of objects like JSONFormatter's, or "text" for lines like LS_FIELDS.
+
+Example:
+    // curl 'http://localhost:8080/debug/logs?level=warn&since=1h&format=text'
+*/
+func (this *RingSink) Handler() http.Handler {
+    return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+        if r.Method != "GET" && r.Method != "HEAD" {
+            w.Header().Set("Allow", "GET, HEAD")
+            http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+            return
+        }
+
+        filter, err := parseMessageFilter(r)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+
+        var formatter Formatter
+        switch format := r.URL.Query().Get("format"); format {
+            case "", "json":
+                formatter = &JSONFormatter{}
+            case "text":
+                formatter = &TemplateFormatter{Style: LS_FIELDS}
+            default:
+                http.Error(w, "Format is not legal: " + format, http.StatusBadRequest)
+                return
+        }
+
+        var messages []Message
+        for _, m := range this.Messages() {
+            if filter.match(&m) {
+                messages = append(messages, m)
+            }
+        }
+        if filter.limit > 0 && len(messages) > filter.limit {
+            messages = messages[len(messages) - filter.limit:]
+        }
+
+        var buf bytes.Buffer
+        if _, ok := formatter.(*JSONFormatter); ok {
+            w.Header().Set("Content-Type", "application/json")
+            buf.WriteByte('[')
+            for i, m := range messages {
+                if i > 0 {
+                    buf.WriteByte(',')
+                }
+                buf.Write(bytes.TrimRight(formatter.Format(m), "\n"))
+            }
+            buf.WriteString("]\n")
+        } else {
+            w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+            for _, m := range messages {
+                buf.Write(formatter.Format(m))
+            }
+        }
+
+        w.Write(buf.Bytes())
+    })
+}
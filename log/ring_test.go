@@ -0,0 +1,111 @@
+package log
+
+import "testing"
+import "encoding/json"
+import "net/http"
+import "net/http/httptest"
+import "strings"
+import "time"
+
+
+func TestRingSink(t *testing.T) {
+
+    if _, err := NewRingSink(0); err == nil {
+        t.Error("Size 0 should not be accepted.")
+    }
+
+    ring, _ := NewRingSink(3)
+    if len(ring.Messages()) != 0 {
+        t.Error("Ring sink should be empty.")
+    }
+
+    for _, s := range []string{"1", "2", "3", "4", "5"} {
+        ring.WriteMessage(Message{Msg: s})
+        if len(ring.Messages()) == 1 && ring.Messages()[0].Msg != "1" {
+            t.Error("Unexpected first message.")
+        }
+    }
+
+    var result []string
+    for _, m := range ring.Messages() {
+        result = append(result, m.Msg)
+    }
+    if strings.Join(result, ",") != "3,4,5" {
+        t.Errorf("Unexpected messages: %v", result)
+    }
+}
+
+
+func TestRingSinkHandler(t *testing.T) {
+
+    ring, _ := NewRingSink(10)
+    now := time.Now()
+
+    ring.WriteMessage(Message{Msg: "started", Level: INFO, Time: now.Add(-2 * time.Hour)})
+    ring.WriteMessage(Message{Msg: "slow request", Level: WARN, Time: now.Add(-30 * time.Minute), Fields: Fields{F("path", "/login")}})
+    ring.WriteMessage(Message{Msg: "debug", Level: DEBUG, Time: now.Add(-time.Minute)})
+    ring.WriteMessage(Message{Msg: "db error", Level: ERROR, Time: now})
+
+    handler := ring.Handler()
+
+    tests := []struct {
+        query   string
+        code    int
+        msgs    string
+    }{
+        {"", http.StatusOK, "started,slow request,debug,db error"},
+        {"level=warn", http.StatusOK, "slow request,db error"},
+        {"since=1h", http.StatusOK, "slow request,debug,db error"},
+        {"until=" + now.Add(-time.Hour).Format(time.RFC3339), http.StatusOK, "started"},
+        {"q=login", http.StatusOK, "slow request"},
+        {"q=error&limit=1", http.StatusOK, "db error"},
+        {"limit=2", http.StatusOK, "debug,db error"},
+        {"level=verbose", http.StatusBadRequest, ""},
+        {"since=yesterday", http.StatusBadRequest, ""},
+        {"format=xml", http.StatusBadRequest, ""},
+    }
+
+    for _, test := range tests {
+        r := httptest.NewRequest("GET", "/logs?" + test.query, nil)
+        w := httptest.NewRecorder()
+        handler.ServeHTTP(w, r)
+
+        if w.Code != test.code {
+            t.Errorf("%q: unexpected code %d", test.query, w.Code)
+            continue
+        }
+        if w.Code != http.StatusOK {
+            continue
+        }
+
+        var result []map[string]interface{}
+        if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+            t.Errorf("%q: %v", test.query, err)
+            continue
+        }
+        var msgs []string
+        for _, m := range result {
+            msgs = append(msgs, m["msg"].(string))
+        }
+        if strings.Join(msgs, ",") != test.msgs {
+            t.Errorf("%q: unexpected messages %v", test.query, msgs)
+        }
+    }
+
+    r := httptest.NewRequest("GET", "/logs?format=text&level=warn", nil)
+    w := httptest.NewRecorder()
+    handler.ServeHTTP(w, r)
+
+    expected := now.Add(-30 * time.Minute).Format(TF_DEFAULT) + " WARN: slow request path=/login\n" +
+        now.Format(TF_DEFAULT) + " ERROR: db error\n"
+    if w.Body.String() != expected {
+        t.Errorf("Unexpected text output: %q", w.Body.String())
+    }
+
+    r = httptest.NewRequest("POST", "/logs", nil)
+    w = httptest.NewRecorder()
+    handler.ServeHTTP(w, r)
+    if w.Code != http.StatusMethodNotAllowed {
+        t.Errorf("Unexpected code of POST: %d", w.Code)
+    }
+}
@@ -0,0 +1,377 @@
+package log
+
+import "compress/gzip"
+import "io"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "regexp"
+import "sort"
+import "strconv"
+import "strings"
+import "sync/atomic"
+import "time"
+
+
+// Get the time format used in rotated filename. Argument "rotate" is a value of Config.Rotate.
+func rotateTimeFormat(rotate int) string {
+    switch rotate {
+        case R_HOURLY:
+            return "2006-01-02_15"
+        case R_MONTHLY:
+            return "2006-01"
+    }
+    // R_DAILY, or size-based rotation only.
+    return "2006-01-02"
+}
+
+
+// Convert t to UTC or local time as Config.Utc, used to decide rotate periods.
+func (this *WriterSink) localTime(t time.Time) time.Time {
+    if this.Utc {
+        return t.UTC()
+    }
+    return t.Local()
+}
+
+
+// Get the beginning of the next rotate period after t, i.e. the next hour, day or month.
+func (this *WriterSink) nextRotateTime(t time.Time) time.Time {
+
+    t = this.localTime(t)
+    year, month, day := t.Date()
+
+    switch this.Rotate {
+        case R_HOURLY:
+            return time.Date(year, month, day, t.Hour() + 1, 0, 0, 0, t.Location())
+        case R_DAILY:
+            return time.Date(year, month, day + 1, 0, 0, 0, 0, t.Location())
+        case R_MONTHLY:
+            return time.Date(year, month + 1, 1, 0, 0, 0, 0, t.Location())
+    }
+
+    return time.Time{}
+}
+
+
+/* Check if need to rotate file log, i.e. the two times are in different rotate periods. If yes, return value is a time string used in filename. If not, return a empty string.
+
+Parameters:
+    last        time of the last write to log file.
+    current     current time.
+*/
+func (this *WriterSink) ifRotate(last, current time.Time) string {
+
+    if this.Rotate == R_NONE {
+        return ""
+    }
+
+    format := rotateTimeFormat(this.Rotate)
+
+    lastTime := this.localTime(last).Format(format)
+    currentTime := this.localTime(current).Format(format)
+
+    if lastTime != currentTime {
+        return lastTime
+    }
+
+    return ""
+}
+
+
+// Rotate file log at the beginning of every hour, day or month, must be called in the log writing goroutine.
+func (this *WriterSink) rotateByTimer() {
+
+    now := time.Now()
+
+    // The timer may fire early if the clock is adjusted.
+    if now.Before(this.next) {
+        this.timer.Reset(time.Until(this.next))
+        return
+    }
+
+    this.rotateByTime(now)
+}
+
+
+// Rotate file log when a rotate period has ended, and set the time of next rotation, must be called in the log writing goroutine.
+func (this *WriterSink) rotateByTime(now time.Time) {
+
+    // Rotate a file which has content only, so no empty file is generated in a quiet period.
+    if this.Shared {
+        this.rotateShared()
+    } else if this.size > 0 {
+        this.rotate(this.localTime(this.next.Add(-time.Nanosecond)).Format(rotateTimeFormat(this.Rotate)))
+    }
+
+    this.next = this.nextRotateTime(now)
+    this.timer.Reset(time.Until(this.next))
+}
+
+
+// Check if need to rotate file log before writing n bytes, because the file will exceed Config.MaxSize.
+func (this *WriterSink) ifSizeRotate(n int) bool {
+    if this.MaxSize <= 0 || this.size == 0 {
+        return false
+    }
+    return this.size + int64(n) > this.MaxSize
+}
+
+
+// Split a basename to name and extension, e.g. "a.log" to "a" and ".log".
+func splitExt(basename string) (base, ext string) {
+    idx := strings.LastIndex(basename, ".")
+    if idx >= 0 {
+        return basename[:idx], basename[idx:]
+    }
+    return basename, ""
+}
+
+
+/* Generate a filename (include path) for file log rotate.
+
+If the rotate pattern includes {seq}, it's replaced by the smallest number (start from 1) which makes a filename not exists, neither does its compressed one.
+
+Parameters:
+    filename    filename of current log
+    timestr     time string that will be added to filename
+*/
+func (this *WriterSink) rotateName(filename, timestr string) string {
+
+    base, ext := splitExt(filepath.Base(filename))
+
+    name := func(seq int) string {
+        replacer := strings.NewReplacer("{time}", timestr, "{basename}", base, "{ext}", ext, "{seq}", strconv.Itoa(seq))
+        return filepath.Join(filepath.Dir(filename), replacer.Replace(this.RotatePattern))
+    }
+
+    if !strings.Contains(this.RotatePattern, "{seq}") {
+        return name(0)
+    }
+
+    for seq := 1; ; seq++ {
+        newFilename := name(seq)
+        if _, err := os.Lstat(newFilename); !os.IsNotExist(err) {
+            continue
+        }
+        if _, err := os.Lstat(newFilename + ".gz"); !os.IsNotExist(err) {
+            continue
+        }
+        return newFilename
+    }
+}
+
+
+// Close the log file, rename it by rotate pattern, then open a new one with the same name. The rotated file is compressed in background if Config.Compress is true, and old rotated files are removed as Config.MaxFiles and Config.MaxAge. Background jobs of different rotations run one by one.
+func (this *WriterSink) rotate(timestr string) {
+
+    filename := this.filename
+    this.closeFile()
+
+    newFilename := this.rotateName(filename, timestr)
+
+    err := os.Rename(filename, newFilename)
+    if err != nil {
+        reportTo(this.logger, err)
+        newFilename = ""
+    } else {
+        atomic.AddUint64(&this.counters.rotations, 1)
+    }
+
+    err = this.openFile()
+    if err != nil {
+        reportTo(this.logger, err)
+    }
+
+    compress := this.Compress && newFilename != ""
+    cleanup := this.MaxFiles > 0 || this.MaxAge > 0
+
+    if compress || cleanup {
+        this.wg.Add(1)
+        go func() {
+            defer this.wg.Done()
+            this.jobMu.Lock()
+            defer this.jobMu.Unlock()
+            if compress {
+                if err := compressFile(newFilename); err != nil {
+                    reportTo(this.logger, err)
+                }
+            }
+            if cleanup {
+                this.cleanup(filename)
+            }
+        }()
+    }
+}
+
+
+// Compress a file to "<filename>.gz" by gzip, then remove the original file. The modification time of the original file is kept.
+func compressFile(filename string) (err error) {
+
+    src, err := os.Open(filename)
+    if err != nil {
+        return
+    }
+    defer src.Close()
+
+    info, err := src.Stat()
+    if err != nil {
+        return
+    }
+
+    // Write to a temporary file first, so that a half-compressed file will not be taken as a rotated file.
+    tmpname := filename + ".gz.tmp"
+    dst, err := os.OpenFile(tmpname, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+    if err != nil {
+        return
+    }
+
+    gz := gzip.NewWriter(dst)
+    _, err = io.Copy(gz, src)
+    if err == nil {
+        err = gz.Close()
+    }
+    if e := dst.Close(); err == nil {
+        err = e
+    }
+    if err != nil {
+        os.Remove(tmpname)
+        return
+    }
+
+    err = os.Chtimes(tmpname, info.ModTime(), info.ModTime())
+    if err != nil {
+        os.Remove(tmpname)
+        return
+    }
+
+    err = os.Rename(tmpname, filename + ".gz")
+    if err != nil {
+        os.Remove(tmpname)
+        return
+    }
+
+    src.Close()
+    return os.Remove(filename)
+}
+
+
+// Compare two names with numbers in them compared by value, e.g. "2016-01-02_9_a.log" is less than "2016-01-02_10_a.log". Used to sort rotated files with the same modification time by {seq}.
+func naturalLess(a, b string) bool {
+
+    isDigit := func(c byte) bool { return c >= '0' && c <= '9' }
+
+    // Get the number at the beginning of s without leading zeros, and the rest of s.
+    number := func(s string) (num, rest string) {
+        i := 0
+        for i < len(s) && isDigit(s[i]) {
+            i++
+        }
+        return strings.TrimLeft(s[:i], "0"), s[i:]
+    }
+
+    for a != "" && b != "" {
+        if isDigit(a[0]) && isDigit(b[0]) {
+            var x, y string
+            x, a = number(a)
+            y, b = number(b)
+            if len(x) != len(y) {
+                return len(x) < len(y)
+            }
+            if x != y {
+                return x < y
+            }
+            continue
+        }
+        if a[0] != b[0] {
+            return a[0] < b[0]
+        }
+        a, b = a[1:], b[1:]
+    }
+    return len(a) < len(b)
+}
+
+
+// Make a regexp which matches basenames of files rotated from filename by rotate pattern, including the compressed ones.
+func (this *WriterSink) rotatedRegexp(filename string) *regexp.Regexp {
+
+    base, ext := splitExt(filepath.Base(filename))
+
+    // Every digit in time format matches a digit.
+    var timeExp strings.Builder
+    for _, c := range rotateTimeFormat(this.Rotate) {
+        if c >= '0' && c <= '9' {
+            timeExp.WriteString("[0-9]")
+        } else {
+            timeExp.WriteString(regexp.QuoteMeta(string(c)))
+        }
+    }
+
+    replacer := strings.NewReplacer(
+        regexp.QuoteMeta("{time}"), timeExp.String(),
+        regexp.QuoteMeta("{basename}"), regexp.QuoteMeta(base),
+        regexp.QuoteMeta("{ext}"), regexp.QuoteMeta(ext),
+        regexp.QuoteMeta("{seq}"), "[0-9]+")
+
+    return regexp.MustCompile("^" + replacer.Replace(regexp.QuoteMeta(this.RotatePattern)) + `(\.gz)?$`)
+}
+
+
+// Remove rotated files of filename which exceed Config.MaxFiles or are older than Config.MaxAge. Errors are reported by the logger.
+func (this *WriterSink) cleanup(filename string) {
+
+    dir := filepath.Dir(filename)
+
+    entries, err := ioutil.ReadDir(dir)
+    if err != nil {
+        reportTo(this.logger, err)
+        return
+    }
+
+    re := this.rotatedRegexp(filename)
+
+    var files []os.FileInfo
+    names := make(map[string]bool)
+    for _, entry := range entries {
+        if entry.Mode().IsRegular() && re.MatchString(entry.Name()) {
+            files = append(files, entry)
+            names[entry.Name()] = true
+        }
+    }
+
+    // A file and its compressed one are counted as one file, e.g. when it's being compressed by another process.
+    n := 0
+    for _, file := range files {
+        if !names[file.Name() + ".gz"] {
+            files[n] = file
+            n++
+        }
+    }
+    files = files[:n]
+
+    // newest first
+    sort.Slice(files, func(i, j int) bool {
+        if files[i].ModTime().Equal(files[j].ModTime()) {
+            return naturalLess(files[j].Name(), files[i].Name())
+        }
+        return files[i].ModTime().After(files[j].ModTime())
+    })
+
+    deadline := time.Now().Add(-this.MaxAge)
+
+    remove := func(name string) {
+        err := os.Remove(filepath.Join(dir, name))
+        if err != nil && !os.IsNotExist(err) {
+            reportTo(this.logger, err)
+        }
+    }
+
+    for i, file := range files {
+        if (this.MaxFiles > 0 && i >= this.MaxFiles) || (this.MaxAge > 0 && file.ModTime().Before(deadline)) {
+            remove(file.Name())
+            if name := strings.TrimSuffix(file.Name(), ".gz"); name != file.Name() && names[name] {
+                remove(name)
+            }
+        }
+    }
+}
@@ -0,0 +1,426 @@
+package log
+
+import "testing"
+import "context"
+import "io/ioutil"
+import "os"
+import "compress/gzip"
+import "time"
+import "path/filepath"
+import "runtime"
+import "sort"
+import "strings"
+
+
+// Create a temporary directory and a log file "test.log" in it.
+func tempLogFile(t *testing.T) (dir string, file *os.File) {
+    dir, err := ioutil.TempDir("", "test_log_")
+    if err != nil {
+        t.Fatal(err)
+    }
+    file, err = OpenFile(filepath.Join(dir, "test.log"))
+    if err != nil {
+        os.RemoveAll(dir)
+        t.Fatal(err)
+    }
+    return
+}
+
+
+func TestSizeRotate(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+
+    var config Config
+    config.Layout      = LY_MSGONLY
+    config.LayoutStyle = "{msg}"
+    config.MaxSize     = 10
+
+    logger, err := New(file, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Each line is 6 bytes, so every file holds one line.
+    for _, s := range []string{"line1", "line2", "line3"} {
+        logger.Info(s)
+    }
+    logger.Wait()
+
+    names, err := filepath.Glob(filepath.Join(dir, "*_*_test.log"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(names) != 2 {
+        t.Fatalf("Expect 2 rotated files, got %v", names)
+    }
+
+    for i, name := range names {
+        b, err := ioutil.ReadFile(name)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if string(b) != "line" + string('1' + rune(i)) + "\n" {
+            t.Errorf("Unexpected content of %s: %q", name, b)
+        }
+    }
+
+    b, err := ioutil.ReadFile(filepath.Join(dir, "test.log"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(b) != "line3\n" {
+        t.Errorf("Unexpected content of current log: %q", b)
+    }
+}
+
+
+func TestRotatePattern(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+    defer file.Close()
+
+    var config Config
+    config.Rotate        = R_DAILY
+    config.MaxSize       = 1024
+    config.RotatePattern = RP_DEFAULT
+
+    _, err := New(file, config)
+    if err == nil || err.Error() != "Rotate pattern should include {seq} when max size is set." {
+        t.Errorf("Unexpected error: %v", err)
+    }
+}
+
+
+func TestRotatedRegexp(t *testing.T) {
+    sink := &WriterSink{}
+    sink.Rotate = R_HOURLY
+    sink.RotatePattern = RP_SEQ
+
+    re := sink.rotatedRegexp("/var/log/app.log")
+
+    for _, name := range []string{"2016-01-02_15_1_app.log", "2016-01-02_15_12_app.log", "2016-01-02_15_1_app.log.gz"} {
+        if !re.MatchString(name) {
+            t.Errorf("%s should match", name)
+        }
+    }
+
+    for _, name := range []string{"app.log", "2016-01-02_1_app.log", "2016-01-02_15_x_app.log", "2016-01-02_15_1_app.log.bak"} {
+        if re.MatchString(name) {
+            t.Errorf("%s should not match", name)
+        }
+    }
+}
+
+
+func TestCleanup(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+
+    var config Config
+    config.Layout      = LY_MSGONLY
+    config.LayoutStyle = "{msg}"
+    config.MaxSize     = 10
+    config.MaxFiles    = 2
+
+    logger, err := New(file, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // An unrelated file should be kept.
+    other := filepath.Join(dir, "other.log")
+    err = ioutil.WriteFile(other, nil, 0640)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for _, s := range []string{"line1", "line2", "line3", "line4", "line5"} {
+        logger.Info(s)
+        logger.Wait()
+    }
+
+    names, err := filepath.Glob(filepath.Join(dir, "*_*_test.log"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(names) != 2 {
+        t.Fatalf("Expect 2 rotated files, got %v", names)
+    }
+
+    if _, err = os.Stat(other); err != nil {
+        t.Error(err)
+    }
+}
+
+
+// A rotated file and its compressed one are counted as one file, and removed together.
+func TestCleanupCompressing(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+
+    var config Config
+    config.Rotate   = R_DAILY
+    config.MaxFiles = 2
+
+    sink, err := NewWriterSink(file, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer sink.Close()
+
+    // From the oldest to the newest, the newest is being compressed.
+    now := time.Now()
+    names := []string{"2016-01-01_test.log.gz", "2016-01-01_test.log", "2016-01-02_test.log.gz", "2016-01-03_test.log", "2016-01-03_test.log.gz"}
+    for i, name := range names {
+        name = filepath.Join(dir, name)
+        if err = ioutil.WriteFile(name, nil, 0640); err != nil {
+            t.Fatal(err)
+        }
+        mtime := now.Add(time.Duration(i - len(names)) * time.Minute)
+        os.Chtimes(name, mtime, mtime)
+    }
+
+    sink.cleanup(file.Name())
+
+    rotated, _ := filepath.Glob(filepath.Join(dir, "*_test.log*"))
+    expected := []string{filepath.Join(dir, "2016-01-02_test.log.gz"), filepath.Join(dir, "2016-01-03_test.log"), filepath.Join(dir, "2016-01-03_test.log.gz")}
+    if strings.Join(rotated, ",") != strings.Join(expected, ",") {
+        t.Errorf("Unexpected rotated files: %v", rotated)
+    }
+}
+
+
+func TestCompress(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+
+    var config Config
+    config.Layout      = LY_MSGONLY
+    config.LayoutStyle = "{msg}"
+    config.MaxSize     = 10
+    config.Compress    = true
+
+    logger, err := New(file, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    logger.Info("line1")
+    logger.Info("line2")
+    logger.Wait()
+
+    names, err := filepath.Glob(filepath.Join(dir, "*_*_test.log*"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(names) != 1 || filepath.Ext(names[0]) != ".gz" {
+        t.Fatalf("Expect 1 compressed file, got %v", names)
+    }
+
+    f, err := os.Open(names[0])
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer f.Close()
+
+    gz, err := gzip.NewReader(f)
+    if err != nil {
+        t.Fatal(err)
+    }
+    b, err := ioutil.ReadAll(gz)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(b) != "line1\n" {
+        t.Errorf("Unexpected content: %q", b)
+    }
+}
+
+
+func TestNextRotateTime(t *testing.T) {
+    sink := &WriterSink{}
+    sink.Utc = true
+
+    now := time.Date(2016, 12, 31, 23, 59, 59, 0, time.UTC)
+
+    tests := []struct {
+        rotate int
+        next time.Time
+    }{
+        {R_HOURLY, time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)},
+        {R_DAILY, time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)},
+        {R_MONTHLY, time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)},
+    }
+
+    for _, test := range tests {
+        sink.Rotate = test.rotate
+        if next := sink.nextRotateTime(now); !next.Equal(test.next) {
+            t.Errorf("Rotate %d: expect %v, got %v", test.rotate, test.next, next)
+        }
+    }
+
+    sink.Rotate = R_HOURLY
+    next := sink.nextRotateTime(time.Date(2016, 3, 1, 10, 0, 0, 0, time.UTC))
+    if !next.Equal(time.Date(2016, 3, 1, 11, 0, 0, 0, time.UTC)) {
+        t.Errorf("Unexpected next rotate time: %v", next)
+    }
+}
+
+
+// A file last written in a past period should be rotated when logger starts.
+func TestRotateOnStart(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+
+    _, err := file.WriteString("old message\n")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    mtime := time.Now().AddDate(0, 0, -2)
+    err = os.Chtimes(file.Name(), mtime, mtime)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var config Config
+    config.Rotate = R_DAILY
+
+    logger, err := New(file, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+    logger.Wait()
+
+    b, err := ioutil.ReadFile(filepath.Join(dir, mtime.Format("2006-01-02") + "_test.log"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(b) != "old message\n" {
+        t.Errorf("Unexpected content: %q", b)
+    }
+}
+
+
+// A message written after a period ends goes to the new file, even if the rotation timer has not fired.
+func TestRotateOnWrite(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+
+    var config Config
+    config.Layout      = LY_MSGONLY
+    config.LayoutStyle = "{msg}"
+    config.Rotate      = R_DAILY
+    config.Utc         = true
+
+    logger, err := New(file, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer logger.Close(context.Background())
+
+    logger.Info("old")
+    logger.Wait()
+
+    // Pretend the day ended an hour ago, and the timer fires tomorrow.
+    var yesterday time.Time
+    done := make(chan struct{})
+    logger.run(func() {
+        sink := logger.primary
+        sink.next = sink.nextRotateTime(time.Now()).AddDate(0, 0, -1)
+        yesterday = sink.next.Add(-time.Hour)
+        close(done)
+    })
+    <-done
+
+    logger.Info("new")
+    logger.Wait()
+
+    b, err := ioutil.ReadFile(filepath.Join(dir, yesterday.Format("2006-01-02") + "_test.log"))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(b) != "old\n" {
+        t.Errorf("Unexpected content of rotated file: %q", b)
+    }
+
+    b, err = ioutil.ReadFile(file.Name())
+    if err != nil {
+        t.Fatal(err)
+    }
+    if string(b) != "new\n" {
+        t.Errorf("Unexpected content: %q", b)
+    }
+}
+
+
+// If the log file could not be created after it's moved, writes fail rather than panic, and the file is created again once the directory is writable.
+func TestRotateOpenFailed(t *testing.T) {
+    if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+        t.Skip("Directory permissions are not enforced.")
+    }
+
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+    defer os.Chmod(dir, 0700)
+
+    logger, err := New(file, Config{Layout: LY_MSGONLY, LayoutStyle: "{msg}", MaxSize: 10})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    logger.Info("line1")
+    logger.Wait()
+
+    filename := filepath.Join(dir, "test.log")
+    if err = os.Rename(filename, filepath.Join(dir, "moved.log")); err != nil {
+        t.Fatal(err)
+    }
+    if err = os.Chmod(dir, 0500); err != nil {
+        t.Fatal(err)
+    }
+
+    if err = logger.Reopen(); err == nil {
+        t.Error("Reopen() should fail in an unwritable directory.")
+    }
+    logger.Info("lost")
+    logger.Wait()
+
+    if err = os.Chmod(dir, 0700); err != nil {
+        t.Fatal(err)
+    }
+
+    // Each line is 6 bytes, so line3 makes the file rotated.
+    logger.Info("line2")
+    logger.Info("line3")
+    logger.Wait()
+
+    stats := logger.Stats()
+    logger.Close(context.Background())
+
+    if stats.WriteErrors == 0 || stats.Rotations == 0 {
+        t.Errorf("Unexpected stats: %+v", stats)
+    }
+
+    // Messages are in the rotated files and the new file, along with reports of write errors.
+    names, _ := filepath.Glob(filepath.Join(dir, "*_*_test.log"))
+    sort.Slice(names, func(i, j int) bool {
+        return naturalLess(names[i], names[j])
+    })
+    var lines []string
+    for _, name := range append(names, filename) {
+        b, err := ioutil.ReadFile(name)
+        if err != nil {
+            t.Fatal(err)
+        }
+        for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+            if !strings.HasPrefix(line, "log: ") {
+                lines = append(lines, line)
+            }
+        }
+    }
+    if strings.Join(lines, ",") != "line2,line3" {
+        t.Errorf("Unexpected messages: %v", lines)
+    }
+}
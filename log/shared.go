@@ -0,0 +1,106 @@
+package log
+
+import "os"
+import "time"
+
+
+// ------------------------------------------------
+// Shared log file
+
+
+/* A log file is shared by several processes when Config.Shared is true. The processes are coordinated by an advisory lock of "<filename>.lock":
+
+Before writing, a process takes a shared lock, and reopens the log file if its path has been rotated by another process, i.e. the inode has changed.
+
+Rotation is decided by the modification time and size of the file rather than the process's own state, and done under an exclusive lock. So the first process writing in a new period rotates the file, and the others just reopen it, the file is rotated once per period.
+*/
+
+
+// Open the lock of a shared log file, and rotate the file if it's last written in a past period.
+func (this *WriterSink) attachShared() (err error) {
+    this.lock, err = openFileLock(this.filename + ".lock", this.mode)
+    if err != nil {
+        return
+    }
+    this.rotateShared()
+    return
+}
+
+
+/* Check the shared log file with the lock held: reopen it if the path has been rotated by another process, then check if it needs rotating before writing n bytes.
+
+If yes, return the time string used in the rotated filename, otherwise return an empty string.
+*/
+func (this *WriterSink) checkShared(n int) string {
+
+    file := this.w.(*os.File)
+    info, err := os.Stat(this.filename)
+    current, e := file.Stat()
+
+    if err != nil || e != nil || !os.SameFile(info, current) {
+        if err = this.reopen(); err != nil {
+            reportTo(this.logger, err)
+            return ""
+        }
+        info, err = this.w.(*os.File).Stat()
+        if err != nil {
+            reportTo(this.logger, err)
+            return ""
+        }
+    }
+
+    // Other processes write to the file too.
+    this.size = info.Size()
+    if this.size == 0 {
+        return ""
+    }
+
+    now := time.Now()
+    if timestr := this.ifRotate(info.ModTime(), now); timestr != "" {
+        return timestr
+    }
+    if this.ifSizeRotate(n) {
+        return this.localTime(now).Format(rotateTimeFormat(this.Rotate))
+    }
+    return ""
+}
+
+
+// Write b to the shared log file, rotate it first as needed.
+func (this *WriterSink) writeShared(b []byte) (n int, err error) {
+
+    err = this.lock.lock(false)
+    if err != nil {
+        return
+    }
+    defer this.lock.unlock()
+
+    if this.checkShared(len(b)) != "" {
+        // The lock may be released while being converted, so check again.
+        err = this.lock.lock(true)
+        if err != nil {
+            return
+        }
+        if timestr := this.checkShared(len(b)); timestr != "" {
+            this.rotate(timestr)
+        }
+    }
+
+    n, err = this.w.Write(b)
+    return
+}
+
+
+// Rotate the shared log file under the exclusive lock if needed, e.g. when the rotation timer fires.
+func (this *WriterSink) rotateShared() {
+
+    if err := this.lock.lock(true); err != nil {
+        reportTo(this.logger, err)
+        return
+    }
+    defer this.lock.unlock()
+
+    if timestr := this.checkShared(0); timestr != "" {
+        this.rotate(timestr)
+    }
+}
@@ -0,0 +1,131 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package log
+
+import "testing"
+import "context"
+import "io/ioutil"
+import "os"
+import "path/filepath"
+import "strings"
+import "time"
+
+
+// Open the same log file for n loggers, like n processes.
+func sharedLoggers(t *testing.T, filename string, n int, config Config) (loggers []*Logger) {
+    config.Shared      = true
+    config.Layout      = LY_MSGONLY
+    config.LayoutStyle = "{msg}"
+
+    for i := 0; i < n; i++ {
+        file, err := OpenFile(filename)
+        if err != nil {
+            t.Fatal(err)
+        }
+        logger, err := New(file, config)
+        if err != nil {
+            t.Fatal(err)
+        }
+        loggers = append(loggers, logger)
+    }
+    return
+}
+
+
+// Read all log files in dir except the lock file, and return lines of them.
+func readLogLines(t *testing.T, dir string) (files int, lines []string) {
+    names, _ := filepath.Glob(filepath.Join(dir, "*"))
+    for _, name := range names {
+        if strings.HasSuffix(name, ".lock") {
+            continue
+        }
+        b, err := ioutil.ReadFile(name)
+        if err != nil {
+            t.Fatal(err)
+        }
+        files++
+        lines = append(lines, strings.Fields(string(b))...)
+    }
+    return
+}
+
+
+func TestSharedRotateOncePerPeriod(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+
+    // The file was written yesterday.
+    file.WriteString("old\n")
+    file.Close()
+    yesterday := time.Now().AddDate(0, 0, -1)
+    os.Chtimes(file.Name(), yesterday, yesterday)
+
+    loggers := sharedLoggers(t, file.Name(), 3, Config{Rotate: R_DAILY})
+    for i, logger := range loggers {
+        logger.Info(string(rune('a' + i)))
+        logger.Wait()
+    }
+    for _, logger := range loggers {
+        logger.Close(context.Background())
+    }
+
+    rotated := filepath.Join(dir, yesterday.Format("2006-01-02") + "_test.log")
+    b, err := ioutil.ReadFile(rotated)
+    if err != nil || string(b) != "old\n" {
+        t.Errorf("Unexpected rotated file: %q, %v", b, err)
+    }
+
+    b, err = ioutil.ReadFile(file.Name())
+    if err != nil || string(b) != "a\nb\nc\n" {
+        t.Errorf("Unexpected log file: %q, %v", b, err)
+    }
+
+    if files, _ := readLogLines(t, dir); files != 2 {
+        t.Errorf("The file should be rotated once, got %d files.", files)
+    }
+}
+
+
+func TestSharedSizeRotate(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+    file.Close()
+
+    loggers := sharedLoggers(t, file.Name(), 2, Config{MaxSize: 20})
+
+    // Every message is 10 bytes, so every file has 2 messages.
+    for i := 0; i < 10; i++ {
+        loggers[i % 2].Infof("message %d", i)
+        loggers[i % 2].Wait()
+    }
+    for _, logger := range loggers {
+        logger.Close(context.Background())
+    }
+
+    files, lines := readLogLines(t, dir)
+    if files != 5 || len(lines) != 20 {
+        t.Errorf("Unexpected log files: %d files, %v", files, lines)
+    }
+}
+
+
+// A process reopens the log file after another one renames it.
+func TestSharedReopen(t *testing.T) {
+    dir, file := tempLogFile(t)
+    defer os.RemoveAll(dir)
+    file.Close()
+
+    loggers := sharedLoggers(t, file.Name(), 1, Config{})
+    loggers[0].Info("first")
+    loggers[0].Wait()
+
+    os.Rename(file.Name(), file.Name() + ".1")
+
+    loggers[0].Info("second")
+    loggers[0].Close(context.Background())
+
+    b, err := ioutil.ReadFile(file.Name())
+    if err != nil || string(b) != "second\n" {
+        t.Errorf("Unexpected log file: %q, %v", b, err)
+    }
+}
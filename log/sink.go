@@ -0,0 +1,278 @@
+package log
+
+import "errors"
+import "fmt"
+import "io"
+import "os"
+import "sync"
+import "sync/atomic"
+import "time"
+
+
+// ------------------------------------------------
+// Sink
+
+
+/* Sink is a destination of log messages.
+
+A Logger calls WriteMessage in its log writing goroutine only, so a sink needs no lock unless it's used by other goroutines too.
+*/
+type Sink interface {
+    WriteMessage(m Message) error
+}
+
+
+// A sink added to Logger and the lowest level of messages it accepts.
+type sinkEntry struct {
+    sink Sink
+    level LevelType
+    counters *sinkCounters
+}
+
+
+// Sinks which need the logger they are added to, e.g. to report errors.
+type attacher interface {
+    attach(logger *Logger) error
+}
+
+
+/* Add a sink to the logger. Messages of "level" and above will be written to the sink, no matter what the logger's level is. Each message goes through the logger's job queue once, and is written to every sink accepting it.
+
+Example:
+    // Write everything to stdout, and warnings and errors to a file too.
+    logger, _ := log.New(os.Stdout, log.Config{Level: log.DEBUG})
+    logger.AddWriter(file, log.Config{Level: log.WARN, Rotate: log.R_DAILY})
+*/
+func (this *Logger) AddSink(sink Sink, level LevelType) error {
+
+    if sink == nil {
+        return errors.New("Sink could not be nil.")
+    }
+
+    if !level.Legal() {
+        return fmt.Errorf("Log level of sink is not legal: %d", level)
+    }
+
+    result := make(chan error, 1)
+    err := this.run(func() {
+        if a, ok := sink.(attacher); ok {
+            if err := a.attach(this); err != nil {
+                result <- err
+                return
+            }
+        }
+
+        entry := sinkEntry{sink: sink, level: level, counters: newSinkCounters(sink)}
+        this.state.sinks = append(this.state.sinks, entry)
+        this.state.counters.addSink(entry.counters)
+
+        if level < LevelType(atomic.LoadInt32(&this.state.sinkLevel)) {
+            atomic.StoreInt32(&this.state.sinkLevel, int32(level))
+        }
+        result <- nil
+    })
+    if err != nil {
+        return err
+    }
+    return <-result
+}
+
+
+// Add a writer to the logger, with its own level, formatter and rotation settings in config. See NewWriterSink() and AddSink().
+func (this *Logger) AddWriter(w io.Writer, config Config) error {
+    sink, err := NewWriterSink(w, config)
+    if err != nil {
+        return err
+    }
+    return this.AddSink(sink, config.Level)
+}
+
+
+// ------------------------------------------------
+// WriterSink
+
+
+// A sink writes messages to an io.Writer by a formatter. If the writer is a file, it could be rotated by time or size.
+type WriterSink struct {
+    Config
+    w io.Writer
+    formatter Formatter
+    size int64          // Size of log file, used by size-based rotation.
+    logger *Logger      // The logger which the sink is added to.
+    wg *waitGroup       // Wait group of background jobs, e.g. compressing rotated files.
+    jobMu sync.Mutex    // Runs background jobs of the sink one at a time, so cleanup never sees a file being compressed as two files.
+    timer *time.Timer   // Timer of time-based rotation.
+    next time.Time      // Time of next time-based rotation.
+    lock *fileLock      // Lock of a shared log file, see Config.Shared.
+    filename string     // Path of the log file, used to open it again after rotation or reopening.
+    mode os.FileMode    // Permission bits of the log file, used to open it again.
+    openFailed bool     // If opening the log file again has failed, the closed file is kept and opening is retried before each write.
+    counters *sinkCounters  // Counters of Logger.Stats().
+}
+
+
+// Create a WriterSink. Zero values in config are set to default as New() does. The rotation of the sink starts after it's added to a logger.
+func NewWriterSink(w io.Writer, config Config) (sink *WriterSink, err error) {
+
+    setConfigDefault(&config)
+
+    if !config.Level.Legal() {
+        err = fmt.Errorf("Log level is not legal: %d", config.Level)
+        return
+    }
+
+    err = isLayoutLegal(config.Layout, config.LayoutStyle)
+    if err != nil {
+        return
+    }
+
+    if !isRotateLegal(config.Rotate) {
+        err = fmt.Errorf("Rotate is not legal: %d", config.Rotate)
+        return
+    }
+
+    if config.MaxSize < 0 {
+        err = fmt.Errorf("Max size is not legal: %d", config.MaxSize)
+        return
+    }
+
+    if config.MaxFiles < 0 || config.MaxAge < 0 {
+        err = errors.New("Max files and max age could not be negative.")
+        return
+    }
+
+    if config.Shared && !sharedSupported {
+        err = errors.New("Shared log file is not supported on this platform.")
+        return
+    }
+
+    if config.Shared {
+        err = reopenableWriter(w)
+    } else {
+        err = ifWriterLegal(w, config.rotatable())
+    }
+    if err != nil {
+        return
+    }
+
+    if config.rotatable() {
+        err = isRotatePatternLegal(config.RotatePattern, config.MaxSize > 0)
+        if err != nil {
+            return
+        }
+    } else {
+        if len(config.RotatePattern) > 0 {
+            err = errors.New("Rotate pattern does not match rotate value.")
+            return
+        }
+    }
+
+    sink = new(WriterSink)
+    sink.Config     = config
+    sink.w          = w
+    sink.formatter  = config.Formatter
+    if sink.formatter == nil {
+        // Newlines in message are kept as before.
+        sink.formatter = &TemplateFormatter{
+            Style:      config.LayoutStyle,
+            TimeFormat: config.TimeFormat,
+            Multiline:  true,
+        }
+    }
+    sink.wg = newWaitGroup()
+    if file, ok := w.(*os.File); ok {
+        sink.filename = file.Name()
+        sink.mode = fileMode(file)
+    }
+    sink.counters = new(sinkCounters)
+
+    return
+}
+
+
+// Add the sink to a logger: a file log of past period is rotated at once, and the timer of time-based rotation starts.
+func (this *WriterSink) attach(logger *Logger) error {
+
+    if this.logger != nil {
+        return errors.New("Sink has been added to a logger.")
+    }
+
+    this.logger = logger
+    this.wg = logger.wg
+
+    if this.Shared {
+        if err := this.attachShared(); err != nil {
+            return err
+        }
+    } else if !this.rotatable() {
+        return nil
+    }
+
+    if !this.Shared {
+        info, err := this.w.(*os.File).Stat()
+        if err != nil {
+            return err
+        }
+        this.size = info.Size()
+
+        // If the log file was last written in a past period (e.g. before the program restarted), rotate it at once.
+        if info.Size() > 0 {
+            if timestr := this.ifRotate(info.ModTime(), time.Now()); timestr != "" {
+                this.rotate(timestr)
+            }
+        }
+    }
+
+    if this.Rotate > R_NONE {
+        this.next = this.nextRotateTime(time.Now())
+        this.timer = time.AfterFunc(time.Until(this.next), func() {
+            logger.run(this.rotateByTimer)
+        })
+    }
+
+    return nil
+}
+
+
+// Write a message, rotate file log by time and size as needed.
+func (this *WriterSink) WriteMessage(m Message) error {
+
+    b := this.msg2bytes(m)
+
+    // The timer fires late after the host is suspended or the clock is set forward, and it's run after messages queued meanwhile, so the period is checked before every write too. The timer only rotates the file in a quiet period. A sink not added to a logger has no timer.
+    if now := time.Now(); this.Rotate > R_NONE && this.timer != nil && !now.Before(this.next) {
+        this.rotateByTime(now)
+    }
+
+    if this.Shared {
+        n, err := this.writeShared(b)
+        this.size += int64(n)
+        atomic.AddUint64(&this.counters.bytes, uint64(n))
+        return err
+    }
+
+    if this.openFailed {
+        if err := this.openFile(); err != nil {
+            return err
+        }
+    }
+
+    if this.ifSizeRotate(len(b)) {
+        this.rotate(this.localTime(time.Now()).Format(rotateTimeFormat(this.Rotate)))
+    }
+
+    n, err := this.w.Write(b)
+    this.size += int64(n)
+    atomic.AddUint64(&this.counters.bytes, uint64(n))
+    return err
+}
+
+
+func (this *WriterSink) msg2bytes(m Message) []byte {
+
+    if this.Utc {
+        m.Time = m.Time.UTC()
+    }
+
+    return this.formatter.Format(m)
+}
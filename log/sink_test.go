@@ -0,0 +1,86 @@
+package log
+
+import "testing"
+import "bytes"
+import "fmt"
+import "os"
+
+
+func ExampleLogger_AddWriter() {
+
+    var config Config
+    config.Layout      = LY_LEVEL
+    config.LayoutStyle = "stdout {level}: {msg}"
+    config.Level       = WARN
+
+    logger, err := New(os.Stdout, config)
+    if err != nil {
+        fmt.Println(err)
+        os.Exit(1)
+    }
+
+    // Another writer with a lower level and a different format.
+    var buf bytes.Buffer
+    err = logger.AddWriter(&buf, Config{Level: DEBUG, Formatter: &LogfmtFormatter{TimeFormat: "-"}})
+    if err != nil {
+        fmt.Println(err)
+        os.Exit(1)
+    }
+
+    logger.Debug("debug")
+    logger.Error("error")
+    logger.Wait()
+
+    fmt.Print(buf.String())
+    // Output: stdout ERROR: error
+    // time=- level=DEBUG msg=debug
+    // time=- level=ERROR msg=error
+}
+
+
+// A sink collects messages for test.
+type messageSink []Message
+
+func (this *messageSink) WriteMessage(m Message) error {
+    *this = append(*this, m)
+    return nil
+}
+
+
+func TestAddSink(t *testing.T) {
+    var buf bytes.Buffer
+
+    var config Config
+    config.Layout      = LY_MSGONLY
+    config.LayoutStyle = "{msg}"
+    config.Level       = ERROR
+
+    logger, err := New(&buf, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    var sink messageSink
+    err = logger.AddSink(&sink, INFO)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    err = logger.AddSink(nil, INFO)
+    if err == nil {
+        t.Error("Nil sink should not be added.")
+    }
+
+    logger.With("k", "v").Debug("debug")
+    logger.With("k", "v").Info("info")
+    logger.Error("error")
+    logger.Wait()
+
+    if buf.String() != "error\n" {
+        t.Errorf("Unexpected output of primary sink: %q", buf.String())
+    }
+
+    if len(sink) != 2 || sink[0].Msg != "info" || sink[0].Fields[0].Key != "k" || sink[1].Msg != "error" {
+        t.Errorf("Unexpected messages: %v", sink)
+    }
+}
@@ -0,0 +1,130 @@
+package log
+
+import "context"
+import "log/slog"
+import "runtime"
+
+
+// ------------------------------------------------
+// SlogHandler
+
+
+/* A slog.Handler writes records through a Logger, so the records go through the logger's job queue, sinks and rotation.
+
+Levels of records are mapped to LevelType: levels below slog.LevelDebug to DEBUG, between slog.LevelDebug and slog.LevelInfo to NOTICE, slog.LevelInfo to INFO, slog.LevelWarn to WARN, slog.LevelError to ERROR, and slog.LevelError+4 and above to FATAL. A FATAL record does not exit the program.
+
+Attributes are passed as fields of messages. Keys of attributes in groups are prefixed with the group names, e.g. "request.id".
+
+Example:
+    slogger := slog.New(log.NewSlogHandler(logger))
+    slogger.Info("user login", "user", 1001)
+*/
+type SlogHandler struct {
+    logger *Logger
+    prefix string       // Prefix of keys made by WithGroup(), e.g. "request.".
+    fields Fields       // Fields made by WithAttrs().
+}
+
+
+// Create a SlogHandler which writes records through logger.
+func NewSlogHandler(logger *Logger) *SlogHandler {
+    return &SlogHandler{logger: logger}
+}
+
+
+// Convert a slog level to LevelType.
+func fromSlogLevel(level slog.Level) LevelType {
+    switch {
+        case level <= slog.LevelDebug:
+            return DEBUG
+        case level < slog.LevelInfo:
+            return NOTICE
+        case level < slog.LevelWarn:
+            return INFO
+        case level < slog.LevelError:
+            return WARN
+        case level < slog.LevelError + 4:
+            return ERROR
+    }
+    return FATAL
+}
+
+
+// Append an attribute to fields. Groups are flattened, keys of attributes in a group are prefixed with the group name.
+func appendAttr(fields Fields, prefix string, attr slog.Attr) Fields {
+
+    attr.Value = attr.Value.Resolve()
+
+    // Empty attributes are ignored, as slog.Handler requires.
+    if attr.Equal(slog.Attr{}) {
+        return fields
+    }
+
+    if attr.Value.Kind() == slog.KindGroup {
+        // Attributes of a group without key are inlined.
+        if attr.Key != "" {
+            prefix += attr.Key + "."
+        }
+        for _, a := range attr.Value.Group() {
+            fields = appendAttr(fields, prefix, a)
+        }
+        return fields
+    }
+
+    return append(fields, Field{Key: prefix + attr.Key, Value: attr.Value.Any()})
+}
+
+
+func (this *SlogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+    return this.logger.enabled(fromSlogLevel(level))
+}
+
+
+func (this *SlogHandler) Handle(ctx context.Context, r slog.Record) error {
+
+    m := newMsg(r.Message, fromSlogLevel(r.Level))
+    if !r.Time.IsZero() {
+        m.Time = r.Time
+    }
+
+    fields := make(Fields, len(this.fields), len(this.fields) + r.NumAttrs())
+    copy(fields, this.fields)
+    r.Attrs(func(attr slog.Attr) bool {
+        fields = appendAttr(fields, this.prefix, attr)
+        return true
+    })
+    m.Fields = fields
+
+    if this.logger.Caller && r.PC != 0 {
+        frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+        m.Caller = Caller{File: frame.File, Line: frame.Line, Func: frame.Function}
+    }
+
+    return this.logger.send(m)
+}
+
+
+func (this *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+    if len(attrs) == 0 {
+        return this
+    }
+
+    handler := *this
+    handler.fields = make(Fields, len(this.fields), len(this.fields) + len(attrs))
+    copy(handler.fields, this.fields)
+    for _, attr := range attrs {
+        handler.fields = appendAttr(handler.fields, this.prefix, attr)
+    }
+    return &handler
+}
+
+
+func (this *SlogHandler) WithGroup(name string) slog.Handler {
+    if name == "" {
+        return this
+    }
+
+    handler := *this
+    handler.prefix = this.prefix + name + "."
+    return &handler
+}
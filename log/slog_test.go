@@ -0,0 +1,94 @@
+package log
+
+import "testing"
+import "bytes"
+import "fmt"
+import "log/slog"
+import "os"
+import "runtime"
+import "strings"
+
+
+func TestFromSlogLevel(t *testing.T) {
+    tests := map[slog.Level]LevelType{
+        slog.LevelDebug - 4:    DEBUG,
+        slog.LevelDebug:        DEBUG,
+        slog.LevelDebug + 2:    NOTICE,
+        slog.LevelInfo:         INFO,
+        slog.LevelWarn:         WARN,
+        slog.LevelError:        ERROR,
+        slog.LevelError + 4:    FATAL,
+    }
+    for level, expected := range tests {
+        if result := fromSlogLevel(level); result != expected {
+            t.Errorf("%v: expect %v, got %v", level, expected, result)
+        }
+    }
+}
+
+
+func TestSlogHandler(t *testing.T) {
+    var buf bytes.Buffer
+
+    var config Config
+    config.Level       = INFO
+    config.Layout      = LY_LEVEL
+    config.LayoutStyle = "{level} {caller}: {msg} {fields}"
+    config.Caller      = true
+
+    logger, err := New(&buf, config)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    slogger := slog.New(NewSlogHandler(logger.With("app", "test")))
+
+    _, _, line, _ := runtime.Caller(0)
+    slogger.Debug("debug")
+    slogger.With("user", 1001).WithGroup("req").Info("login", "id", "a1", slog.Group("client", "ip", "10.0.0.1"), slog.Group("empty"))
+    slogger.Error("failed", slog.Any("", nil), "err", fmt.Errorf("timeout"))
+    logger.Wait()
+
+    expected := fmt.Sprintf("INFO log/slog_test.go:%d: login app=test user=1001 req.id=a1 req.client.ip=10.0.0.1\n", line + 2) +
+        fmt.Sprintf("ERROR log/slog_test.go:%d: failed app=test err=timeout\n", line + 3)
+    if buf.String() != expected {
+        t.Errorf("Unexpected output: %q", buf.String())
+    }
+}
+
+
+func ExampleNewSlogHandler() {
+
+    var config Config
+    config.Formatter = &JSONFormatter{TimeFormat: "-"}
+
+    logger, err := New(os.Stdout, config)
+    if err != nil {
+        fmt.Println(err)
+        os.Exit(1)
+    }
+
+    slogger := slog.New(NewSlogHandler(logger))
+    slogger.Warn("disk is almost full", "usage", 0.95, slog.Group("disk", "path", "/data"))
+    logger.Wait()
+    // Output: {"time":"-","level":"WARN","msg":"disk is almost full","usage":0.95,"disk.path":"/data"}
+}
+
+
+// The stack trace should start from the caller of slog.Logger.
+func TestSlogHandlerStack(t *testing.T) {
+    var sink messageSink
+
+    logger, err := New(&bytes.Buffer{}, Config{Stack: true, StackLevel: ERROR})
+    if err != nil {
+        t.Fatal(err)
+    }
+    logger.AddSink(&sink, DEBUG)
+
+    slog.New(NewSlogHandler(logger)).Error("error")
+    logger.Wait()
+
+    if len(sink) != 1 || !strings.HasPrefix(sink[0].Stack, pkgPrefix + "TestSlogHandlerStack\n") {
+        t.Errorf("Unexpected messages: %v", sink)
+    }
+}
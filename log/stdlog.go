@@ -0,0 +1,116 @@
+package log
+
+import "io"
+import stdlog "log"
+import "regexp"
+import "strings"
+
+
+// ------------------------------------------------
+// Bridge of the standard library's log package
+
+
+// Timestamps added by other loggers at the beginning of a line, e.g. "2016/01/02 15:04:05.000000 " of the standard library's log package, or "2016-01-02T15:04:05Z " in RFC 3339. Used when flags of the other logger are unknown.
+var timestampRegexp = regexp.MustCompile(`^(` +
+    `(\d{4}/\d{2}/\d{2} )?\d{2}:\d{2}:\d{2}(\.\d+)? ` + `|` +
+    `\d{4}/\d{2}/\d{2} ` + `|` +
+    `\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})? ` +
+    `)`)
+
+
+// Make a regexp which matches the timestamp added by the standard library's log package with flags. If flags have no date or time, nil is returned.
+func stdlogTimestampRegexp(flags int) *regexp.Regexp {
+
+    exp := ""
+    if flags & stdlog.Ldate != 0 {
+        exp += `\d{4}/\d{2}/\d{2} `
+    }
+    if flags & (stdlog.Ltime | stdlog.Lmicroseconds) != 0 {
+        exp += `\d{2}:\d{2}:\d{2}`
+        if flags & stdlog.Lmicroseconds != 0 {
+            exp += `\.\d{6}`
+        }
+        exp += ` `
+    }
+
+    if exp == "" {
+        return nil
+    }
+    return regexp.MustCompile("^" + exp)
+}
+
+
+// An io.Writer writes every line as a message of a level.
+type levelWriter struct {
+    logger *Logger
+    level LevelType
+    timestamp *regexp.Regexp    // Timestamp removed from the beginning of lines, nil if none.
+}
+
+
+/* Get an io.Writer for other loggers, each line written to it is logged as a message of level. Empty lines are ignored, and a timestamp at the beginning of a line is removed because the message has its own time.
+
+If flags of a standard library's logger writing to it are given, only the timestamp of the flags is removed, and nothing is removed if the flags have no date or time. Otherwise timestamps in common formats are removed, see timestampRegexp.
+
+Every Write() is taken as whole lines, a line without trailing newline is not buffered for the next Write().
+
+Example:
+    // A logger of the standard library.
+    errorLog := stdlog.New(logger.Writer(log.ERROR, stdlog.LstdFlags), "", stdlog.LstdFlags)
+*/
+func (this *Logger) Writer(level LevelType, flags ...int) io.Writer {
+    w := &levelWriter{logger: this, level: level, timestamp: timestampRegexp}
+    if len(flags) > 0 {
+        w.timestamp = stdlogTimestampRegexp(flags[0])
+    }
+    return w
+}
+
+
+// Log each line of b. If a message could not be sent, the number of bytes of lines sent before it is returned.
+func (this *levelWriter) Write(b []byte) (int, error) {
+
+    if !this.logger.enabled(this.level) {
+        return len(b), nil
+    }
+
+    n := 0
+    for _, line := range strings.SplitAfter(string(b), "\n") {
+        msg := strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+        if this.timestamp != nil {
+            msg = this.timestamp.ReplaceAllString(msg, "")
+        }
+        if msg != "" {
+            if err := this.logger.send(newMsg(msg, this.level)); err != nil {
+                return n, err
+            }
+        }
+        n += len(line)
+    }
+
+    return n, nil
+}
+
+
+/* Redirect the output of the standard library's log package to the logger, messages are logged as level. Date and time flags of the standard logger are cleared to avoid duplicate timestamps, other flags and the prefix are kept. Messages are logged as is, a timestamp-like text at the beginning is not removed.
+
+The return value restores the output and flags of the standard logger.
+
+Example:
+    restore := logger.RedirectStdLog(log.WARN)
+    defer restore()
+*/
+func (this *Logger) RedirectStdLog(level LevelType) (restore func()) {
+
+    output := stdlog.Writer()
+    flags := stdlog.Flags()
+
+    newFlags := flags &^ (stdlog.Ldate | stdlog.Ltime | stdlog.Lmicroseconds | stdlog.LUTC)
+    stdlog.SetOutput(this.Writer(level, newFlags))
+    stdlog.SetFlags(newFlags)
+
+    return func() {
+        stdlog.SetOutput(output)
+        stdlog.SetFlags(flags)
+    }
+}
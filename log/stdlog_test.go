@@ -0,0 +1,110 @@
+package log
+
+import "testing"
+import "bytes"
+import stdlog "log"
+import "runtime"
+import "fmt"
+import "context"
+
+
+func TestLevelWriter(t *testing.T) {
+    var buf bytes.Buffer
+
+    logger, err := New(&buf, Config{Layout: LY_LEVEL, LayoutStyle: "{level} {msg}", Level: INFO})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    w := logger.Writer(WARN)
+    for _, s := range []string{
+        "2016/01/02 15:04:05 first\n",
+        "2016/01/02 15:04:05.123456 second\nthird\n\n",
+        "15:04:05 fourth",
+        "2016-01-02T15:04:05.123+08:00 fifth\r\n",
+        "2016 is not a timestamp\n",
+    } {
+        if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+            t.Errorf("Write(%q) returns %d, %v", s, n, err)
+        }
+    }
+
+    // Messages below the logger's level are ignored.
+    logger.Writer(DEBUG).Write([]byte("debug\n"))
+
+    logger.Write([]byte("info\n"))
+    logger.Wait()
+
+    expected := "WARN first\nWARN second\nWARN third\nWARN fourth\nWARN fifth\nWARN 2016 is not a timestamp\nINFO info\n"
+    if buf.String() != expected {
+        t.Errorf("Unexpected output: %q", buf.String())
+    }
+}
+
+
+func TestRedirectStdLog(t *testing.T) {
+    var buf bytes.Buffer
+
+    logger, err := New(&buf, Config{Layout: LY_LEVEL, LayoutStyle: "{level} {caller}: {msg}", Caller: true})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    stdlog.SetFlags(stdlog.LstdFlags)
+    stdlog.SetPrefix("app: ")
+    restore := logger.RedirectStdLog(ERROR)
+
+    _, _, line, _ := runtime.Caller(0)
+    stdlog.Print("first\nsecond")
+    stdlog.Print("12:00:00 meeting")
+
+    restore()
+    stdlog.SetPrefix("")
+    logger.Wait()
+
+    if stdlog.Flags() != stdlog.LstdFlags {
+        t.Errorf("Flags are not restored: %d", stdlog.Flags())
+    }
+
+    expected := fmt.Sprintf("ERROR log/stdlog_test.go:%d: app: first\nERROR log/stdlog_test.go:%d: second\nERROR log/stdlog_test.go:%d: app: 12:00:00 meeting\n", line + 1, line + 1, line + 2)
+    if buf.String() != expected {
+        t.Errorf("Unexpected output: %q", buf.String())
+    }
+}
+
+
+func TestLevelWriterFlags(t *testing.T) {
+    var buf bytes.Buffer
+
+    logger, err := New(&buf, Config{Layout: LY_MSGONLY, LayoutStyle: "{msg}"})
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    tests := []struct {
+        flags   int
+        line    string
+        msg     string
+    }{
+        {0, "15:04:05 no time flag", "15:04:05 no time flag"},
+        {stdlog.LstdFlags, "2016/01/02 15:04:05 std flags", "std flags"},
+        {stdlog.LstdFlags, "2016-01-02T15:04:05Z not std", "2016-01-02T15:04:05Z not std"},
+        {stdlog.Lmicroseconds, "15:04:05.123456 microseconds", "microseconds"},
+        {stdlog.Ldate, "2016/01/02 15:04:05 date only", "15:04:05 date only"},
+    }
+
+    for _, test := range tests {
+        buf.Reset()
+        logger.Writer(INFO, test.flags).Write([]byte(test.line + "\n"))
+        logger.Wait()
+        if buf.String() != test.msg + "\n" {
+            t.Errorf("Flags %d: expected %q, got %q", test.flags, test.msg, buf.String())
+        }
+    }
+
+    logger.Close(context.Background())
+
+    if n, err := logger.Writer(INFO).Write([]byte("a\nb\n")); n != 0 || err != ErrClosed {
+        t.Errorf("Unexpected result of writing to closed logger: %d, %v", n, err)
+    }
+}
@@ -0,0 +1,155 @@
+package log
+
+import "fmt"
+import "os"
+import "path/filepath"
+import "strconv"
+import "strings"
+
+
+// ------------------------------------------------
+// SyslogSink
+
+
+// Syslog message format.
+const (
+    SF_RFC5424 = iota   // The syslog protocol, e.g. "<14>1 2016-01-02T15:04:05.000000+08:00 host app 1234 - - msg".
+    SF_RFC3164          // The BSD syslog protocol, e.g. "<14>Jan  2 15:04:05 host app[1234]: msg".
+)
+
+
+// Settings of SyslogSink.
+type SyslogConfig struct {
+    Format      int         // See SF_RFC5424 and SF_RFC3164.
+    Facility    int         // Facility code, e.g. 16 for local0. If zero, 1 (user) is used.
+    Hostname    string      // If empty, os.Hostname() is used.
+    Tag         string      // Application name. If empty, the program's name is used.
+    NetConfig
+}
+
+
+// Syslog severity of log levels.
+var syslogSeverity = map[LevelType]int{
+    DEBUG:  7,
+    INFO:   6,
+    NOTICE: 5,
+    WARN:   4,
+    ERROR:  3,
+    FATAL:  2,
+}
+
+
+/* A sink sends messages to a syslog server over UDP, TCP or unix socket.
+
+Over TCP and stream unix socket, RFC 5424 messages are framed by octet counting as RFC 6587, and RFC 3164 messages are separated by newlines.
+
+Example:
+    sink, err := log.NewSyslogSink("udp", "localhost:514", log.SyslogConfig{Tag: "app"})
+    if err != nil {
+        return err
+    }
+    logger.AddSink(sink, log.NOTICE)
+*/
+type SyslogSink struct {
+    *shipper
+    config SyslogConfig
+    stream bool
+    pid int
+}
+
+
+// Create a SyslogSink. Network could be "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "unix" or "unixgram".
+func NewSyslogSink(network, addr string, config SyslogConfig) (*SyslogSink, error) {
+
+    switch network {
+        case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", "unix", "unixgram":
+        default:
+            return nil, fmt.Errorf("Network is not supported: %s", network)
+    }
+
+    if config.Format != SF_RFC5424 && config.Format != SF_RFC3164 {
+        return nil, fmt.Errorf("Syslog format is not legal: %d", config.Format)
+    }
+
+    if config.Facility == 0 {
+        config.Facility = 1
+    }
+    if config.Facility < 0 || config.Facility > 23 {
+        return nil, fmt.Errorf("Syslog facility is not legal: %d", config.Facility)
+    }
+
+    if config.Hostname == "" {
+        config.Hostname, _ = os.Hostname()
+    }
+    if config.Tag == "" {
+        config.Tag = filepath.Base(os.Args[0])
+    }
+
+    setNetConfigDefault(&config.NetConfig)
+    if err := isNetConfigLegal(&config.NetConfig); err != nil {
+        return nil, err
+    }
+
+    sender := &connSender{network: network, addr: addr, timeout: config.Timeout}
+
+    sink := new(SyslogSink)
+    sink.config  = config
+    sink.stream  = isStreamNetwork(network)
+    sink.pid     = os.Getpid()
+    sink.shipper = newShipper(config.NetConfig, 100, 0, sender.send, sender.close)
+    return sink, nil
+}
+
+
+// Replace empty or space-containing header fields with "-", the nil value of RFC 5424.
+func syslogHeaderField(s string) string {
+    if s == "" {
+        return "-"
+    }
+    return strings.Join(strings.Fields(s), "_")
+}
+
+
+// Format a message in syslog format, framed if the network is stream oriented.
+func (this *SyslogSink) format(m Message) []byte {
+
+    pri := this.config.Facility * 8 + syslogSeverity[m.Level]
+
+    msg := m.Msg
+    if len(m.Fields) > 0 {
+        msg += " " + m.Fields.String()
+    }
+
+    var s string
+    if this.config.Format == SF_RFC5424 {
+        s = fmt.Sprintf("<%d>1 %s %s %s %d - - %s", pri,
+            m.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+            syslogHeaderField(this.config.Hostname),
+            syslogHeaderField(this.config.Tag),
+            this.pid,
+            msg)
+
+        if this.stream {
+            // Octet counting.
+            s = strconv.Itoa(len(s)) + " " + s
+        }
+    } else {
+        s = fmt.Sprintf("<%d>%s %s %s[%d]: %s", pri,
+            m.Time.Local().Format("Jan _2 15:04:05"),
+            syslogHeaderField(this.config.Hostname),
+            syslogHeaderField(this.config.Tag),
+            this.pid,
+            msg)
+
+        if this.stream {
+            s = newlineEscaper.Replace(s) + "\n"
+        }
+    }
+
+    return []byte(s)
+}
+
+
+func (this *SyslogSink) WriteMessage(m Message) error {
+    return this.push(this.format(m))
+}
@@ -0,0 +1,15 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package log
+
+import "os"
+import "syscall"
+import "unsafe"
+
+
+// Check if a file is a terminal, by getting its terminal attributes.
+func isTerminal(file *os.File) bool {
+    var termios syscall.Termios
+    _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), syscall.TIOCGETA, uintptr(unsafe.Pointer(&termios)))
+    return errno == 0
+}
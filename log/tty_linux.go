@@ -0,0 +1,13 @@
+package log
+
+import "os"
+import "syscall"
+import "unsafe"
+
+
+// Check if a file is a terminal, by getting its terminal attributes.
+func isTerminal(file *os.File) bool {
+    var termios syscall.Termios
+    _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&termios)))
+    return errno == 0
+}
@@ -0,0 +1,11 @@
+//go:build !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd && !windows
+
+package log
+
+import "os"
+
+
+// Terminals are not detected on this platform, so color is off by default.
+func isTerminal(file *os.File) bool {
+    return false
+}
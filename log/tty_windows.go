@@ -0,0 +1,11 @@
+package log
+
+import "os"
+import "syscall"
+
+
+// Check if a file is a console.
+func isTerminal(file *os.File) bool {
+    var mode uint32
+    return syscall.GetConsoleMode(syscall.Handle(file.Fd()), &mode) == nil
+}